@@ -4,11 +4,15 @@ import (
 	"context"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Claims is the only token payload the API signs and accepts.
 type Claims struct {
-	ID   string `json:"id"`
-	Role string `json:"role"`
+	ID        primitive.ObjectID `json:"id"`
+	Email     string             `json:"email"`
+	Role      string             `json:"role"`
+	SessionID primitive.ObjectID `json:"sid"`
 	jwt.RegisteredClaims
 }
 
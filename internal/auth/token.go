@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultIssuer     = "easycheck"
+	defaultAudience   = "easycheck-api"
+	defaultTTL        = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+type TokenConfig struct {
+	Secret     []byte
+	Issuer     string
+	Audience   string
+	TTL        time.Duration
+	RefreshTTL time.Duration
+}
+
+// TokenConfigFromEnv reads JWT_SECRET_KEY, JWT_ISSUER, JWT_AUDIENCE, JWT_TTL
+// and JWT_REFRESH_TTL (Go durations such as "15m"), falling back to defaults
+// for all but the secret.
+func TokenConfigFromEnv() (TokenConfig, error) {
+	cfg := TokenConfig{
+		Secret:     []byte(os.Getenv("JWT_SECRET_KEY")),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		TTL:        defaultTTL,
+		RefreshTTL: defaultRefreshTTL,
+	}
+
+	for name, dst := range map[string]*time.Duration{"JWT_TTL": &cfg.TTL, "JWT_REFRESH_TTL": &cfg.RefreshTTL} {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*dst = d
+		}
+	}
+
+	return cfg, nil
+}
+
+// TokenService issues and verifies the API's access tokens.
+type TokenService struct {
+	cfg TokenConfig
+}
+
+func NewTokenService(cfg TokenConfig) (*TokenService, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("JWT secret is empty, set JWT_SECRET_KEY")
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultIssuer
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = defaultAudience
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.RefreshTTL <= 0 {
+		cfg.RefreshTTL = defaultRefreshTTL
+	}
+
+	return &TokenService{cfg: cfg}, nil
+}
+
+// RefreshTTL is how long a refresh token stays usable.
+func (t *TokenService) RefreshTTL() time.Duration {
+	return t.cfg.RefreshTTL
+}
+
+// Issue signs an access token for the given user and session family and
+// returns it with its expiry.
+func (t *TokenService) Issue(id primitive.ObjectID, email string, role string, sessionID primitive.ObjectID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.cfg.TTL)
+
+	claims := &Claims{
+		ID:        id,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.cfg.Issuer,
+			Subject:   id.Hex(),
+			Audience:  jwt.ClaimStrings{t.cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.cfg.Secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
+// Verify parses tokenString and checks its signature, expiry, issuer and
+// audience.
+func (t *TokenService) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return t.cfg.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(t.cfg.Issuer),
+		jwt.WithAudience(t.cfg.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token is not valid")
+	}
+	if claims.ID.IsZero() {
+		return nil, errors.New("token has no subject id")
+	}
+
+	return claims, nil
+}
+
+// NewRefreshToken returns an opaque refresh token and the hash under which it
+// is stored. Only the hash is ever persisted.
+func NewRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package checkin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"money-minder/internal/types"
+	"time"
+)
+
+var (
+	ErrInvalidCode = errors.New("invalid check-in code")
+	ErrExpiredCode = errors.New("check-in code has expired")
+)
+
+// NewCode returns a random 6-digit code.
+func NewCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashCode keys the code to its window so the stored hash is useless for
+// any other window.
+func HashCode(w *types.CheckInWindow, code string) string {
+	mac := hmac.New(sha256.New, w.Key)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCode checks code against the code currently set on w.
+func VerifyCode(w *types.CheckInWindow, code string, t time.Time) error {
+	if !w.IsOpen(t) {
+		return ErrWindowClosed
+	}
+	if w.CodeHash == "" || !hmac.Equal([]byte(HashCode(w, code)), []byte(w.CodeHash)) {
+		return ErrInvalidCode
+	}
+	if w.CodeExpiresAt == nil || !t.Before(*w.CodeExpiresAt) {
+		return ErrExpiredCode
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package checkin
+
+import (
+	"errors"
+	"math"
+	"money-minder/internal/types"
+)
+
+var (
+	ErrOutsideGeoFence = errors.New("you are not in the classroom")
+	ErrImpreciseFix    = errors.New("your location is not precise enough")
+)
+
+// MaxAccuracyMeters is the widest accuracy circle that can make a position
+// outside a reject fence uncertain. Clients report their own accuracy, so
+// without a cap any position could claim to overlap the fence.
+const MaxAccuracyMeters = 100
+
+const earthRadiusMeters = 6371000
+
+// Position is where a student's device says it is.
+type Position struct {
+	Latitude       float64
+	Longitude      float64
+	AccuracyMeters float64
+}
+
+// Distance returns the great-circle distance in meters between two points.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// EvaluateGeoFence decides whether pos is inside fence. A position whose
+// accuracy circle overlaps the fence is uncertain and goes to review, unless
+// a reject fence refuses it for being less precise than MaxAccuracyMeters.
+// It returns ErrOutsideGeoFence or ErrImpreciseFix when the fence rejects
+// the check-in, and a nil check when there is no fence.
+func EvaluateGeoFence(fence *types.GeoFence, pos *Position) (*types.GeoCheck, error) {
+	if fence == nil {
+		return nil, nil
+	}
+
+	check := &types.GeoCheck{RadiusMeters: fence.RadiusMeters}
+
+	if pos == nil {
+		check.Decision = types.GeoMissing
+	} else {
+		check.Latitude = pos.Latitude
+		check.Longitude = pos.Longitude
+		check.AccuracyMeters = pos.AccuracyMeters
+		check.DistanceMeters = Distance(fence.Latitude, fence.Longitude, pos.Latitude, pos.Longitude)
+
+		switch {
+		case check.DistanceMeters <= fence.RadiusMeters:
+			check.Decision = types.GeoInside
+		case check.DistanceMeters-pos.AccuracyMeters <= fence.RadiusMeters:
+			check.Decision = types.GeoUncertain
+		default:
+			check.Decision = types.GeoOutside
+		}
+	}
+
+	switch {
+	case check.Decision == types.GeoInside:
+		return check, nil
+	case fence.Mode == types.GeoFenceReject && check.Decision == types.GeoUncertain && check.AccuracyMeters > MaxAccuracyMeters:
+		return check, ErrImpreciseFix
+	case check.Decision == types.GeoUncertain || fence.Mode == types.GeoFenceFlag:
+		check.Flagged = true
+		return check, nil
+	default:
+		return check, ErrOutsideGeoFence
+	}
+}
+
+// ValidateGeoFence reports what is wrong with fence, if anything.
+func ValidateGeoFence(fence *types.GeoFence) error {
+	switch {
+	case fence.Latitude < -90 || fence.Latitude > 90:
+		return errors.New("latitude must be between -90 and 90")
+	case fence.Longitude < -180 || fence.Longitude > 180:
+		return errors.New("longitude must be between -180 and 180")
+	case fence.RadiusMeters <= 0:
+		return errors.New("radiusMeters must be positive")
+	case fence.Mode != types.GeoFenceReject && fence.Mode != types.GeoFenceFlag:
+		return errors.New("mode must be reject or flag")
+	}
+	return nil
+}
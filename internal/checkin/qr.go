@@ -0,0 +1,39 @@
+package checkin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// PNG renders content as a size x size pixel QR code.
+func PNG(content string, size int) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, size)
+}
+
+// SVG renders content as a QR code that scales to any size, one unit per
+// module including the quiet zone.
+func SVG(content string) ([]byte, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+
+	bitmap := qr.Bitmap()
+	n := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
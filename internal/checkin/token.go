@@ -0,0 +1,123 @@
+// Package checkin signs and verifies the rotating tokens students scan to
+// check themselves in, and renders them as QR codes.
+package checkin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"money-minder/internal/types"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid check-in token")
+	ErrExpiredToken = errors.New("check-in token has expired")
+	ErrWindowClosed = errors.New("check-in window is closed")
+)
+
+// rotationGrace is how long a token keeps working after it rotates, to
+// absorb the delay between scanning and submitting.
+const rotationGrace = 5 * time.Second
+
+// Token returns the token shown for w at t and the moment it rotates.
+func Token(w *types.CheckInWindow, t time.Time) (string, time.Time) {
+	step := stepAt(w, t)
+
+	payload := make([]byte, 12+8)
+	copy(payload, w.ID[:])
+	binary.BigEndian.PutUint64(payload[12:], uint64(step))
+
+	token := base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(w, step))
+	return token, stepStart(w, step+1)
+}
+
+// WindowID extracts the window a token claims to belong to, without
+// verifying it.
+func WindowID(token string) (primitive.ObjectID, error) {
+	payload, _, err := split(token)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	var id primitive.ObjectID
+	copy(id[:], payload[:12])
+	return id, nil
+}
+
+// Verify checks that token was issued for w and is the one currently
+// displayed, or the previous one within the rotation grace period.
+func Verify(w *types.CheckInWindow, token string, t time.Time) error {
+	payload, mac, err := split(token)
+	if err != nil {
+		return err
+	}
+
+	var id primitive.ObjectID
+	copy(id[:], payload[:12])
+	step := int64(binary.BigEndian.Uint64(payload[12:]))
+
+	if id != w.ID || !hmac.Equal(mac, sign(w, step)) {
+		return ErrInvalidToken
+	}
+	if !w.IsOpen(t) {
+		return ErrWindowClosed
+	}
+
+	current := stepAt(w, t)
+	switch {
+	case step == current:
+		return nil
+	case step == current-1 && t.Before(stepStart(w, current).Add(rotationGrace)):
+		return nil
+	default:
+		return ErrExpiredToken
+	}
+}
+
+// sign binds a step to the window and its course.
+func sign(w *types.CheckInWindow, step int64) []byte {
+	mac := hmac.New(sha256.New, w.Key)
+	mac.Write(w.ID[:])
+	mac.Write(w.CourseID[:])
+	binary.Write(mac, binary.BigEndian, step)
+	return mac.Sum(nil)[:16]
+}
+
+func split(token string) ([]byte, []byte, error) {
+	encPayload, encMac, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil || len(payload) != 20 {
+		return nil, nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encMac)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return payload, mac, nil
+}
+
+func rotation(w *types.CheckInWindow) time.Duration {
+	return time.Duration(w.RotateSeconds) * time.Second
+}
+
+func stepAt(w *types.CheckInWindow, t time.Time) int64 {
+	if t.Before(w.OpensAt) {
+		return 0
+	}
+	return int64(t.Sub(w.OpensAt) / rotation(w))
+}
+
+func stepStart(w *types.CheckInWindow, step int64) time.Time {
+	return w.OpensAt.Add(time.Duration(step) * rotation(w))
+}
@@ -28,6 +28,25 @@ var (
 	uri      = os.Getenv("DB_URI")
 )
 
+const (
+	DriverMongo  = "mongo"
+	DriverMemory = "memory"
+)
+
+// Driver returns the storage backend selected through DB_DRIVER, defaulting
+// to MongoDB. Any other value is rejected so a typo does not silently fall
+// back to Mongo.
+func Driver() (string, error) {
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "":
+		return DriverMongo, nil
+	case DriverMongo, DriverMemory:
+		return driver, nil
+	default:
+		return "", fmt.Errorf("invalid DB_DRIVER %q: must be %q or %q", driver, DriverMongo, DriverMemory)
+	}
+}
+
 func New() Service {
 	once.Do(func() {
 		if uri == "" {
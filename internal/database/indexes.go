@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index is an index the API relies on, either for speed or, when Unique is
+// set, to keep duplicates out of a collection.
+type Index struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+	// Partial restricts the index to the documents matching it.
+	Partial bson.M
+	// ExpireAfter, when set, makes a TTL index on a single date key: MongoDB
+	// deletes documents that long after that date.
+	ExpireAfter *time.Duration
+}
+
+var expireAtDate = time.Duration(0)
+
+// Indexes is every index the repositories expect. EnsureIndexes keeps the
+// database in line with it on startup.
+var Indexes = []Index{
+	{Collection: "students", Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{Collection: "teachers", Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{Collection: "admins", Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{
+		Collection: "courses", Name: "code_unique", Keys: bson.D{{Key: "code", Value: 1}}, Unique: true,
+		Partial: bson.M{"code": bson.M{"$gt": ""}},
+	},
+	{Collection: "courses", Name: "teacher", Keys: bson.D{{Key: "teacher", Value: 1}}},
+	{
+		Collection: "enrollments", Name: "course_user_role_unique", Unique: true,
+		Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "role", Value: 1}},
+	},
+	{Collection: "enrollments", Name: "user_status", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}}},
+	// One record per student, course and day, except that every session
+	// of the day gets its own; records without a session share a null one.
+	{
+		Collection: "attendances", Name: "course_student_date_unique", Unique: true,
+		Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "student_id", Value: 1}, {Key: "day", Value: 1}, {Key: "session_id", Value: 1}},
+	},
+	{
+		Collection: "attendances", Name: "session_student_unique", Unique: true,
+		Keys:    bson.D{{Key: "session_id", Value: 1}, {Key: "student_id", Value: 1}},
+		Partial: bson.M{"session_id": bson.M{"$exists": true}},
+	},
+	// Attendance lists page by date and then _id.
+	{Collection: "attendances", Name: "course_date", Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Collection: "attendances", Name: "student", Keys: bson.D{{Key: "student_id", Value: 1}, {Key: "date", Value: 1}, {Key: "_id", Value: 1}}},
+	{Collection: "class_sessions", Name: "course_start", Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "start", Value: 1}}},
+	{Collection: "checkin_windows", Name: "course_closes_at", Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "closes_at", Value: 1}}},
+	{
+		Collection: "checkin_attempts", Name: "window_student_unique", Unique: true,
+		Keys: bson.D{{Key: "window_id", Value: 1}, {Key: "student_id", Value: 1}},
+	},
+	{Collection: "sessions", Name: "token_hash_unique", Keys: bson.D{{Key: "token_hash", Value: 1}}, Unique: true},
+	{Collection: "sessions", Name: "family", Keys: bson.D{{Key: "family_id", Value: 1}}},
+	{Collection: "sessions", Name: "expires_at_ttl", Keys: bson.D{{Key: "expires_at", Value: 1}}, ExpireAfter: &expireAtDate},
+	{Collection: "justifications", Name: "course_status", Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "status", Value: 1}}},
+	{Collection: "justifications", Name: "student", Keys: bson.D{{Key: "student_id", Value: 1}}},
+	{
+		Collection: "risk_assessments", Name: "course_student_unique", Unique: true,
+		Keys: bson.D{{Key: "course_id", Value: 1}, {Key: "student_id", Value: 1}},
+	},
+}
+
+// Codes MongoDB answers with when an index of the same name or keys
+// already exists with other options.
+const (
+	codeIndexOptionsConflict  = 85
+	codeIndexKeySpecsConflict = 86
+)
+
+// EnsureIndexes creates the given indexes. An index whose definition
+// changed in code is dropped and built again. Creating a unique index fails
+// while the collection still holds duplicates, which must be cleaned up by
+// hand first.
+func EnsureIndexes(ctx context.Context, s Service, indexes []Index) error {
+	for _, idx := range indexes {
+		collection := s.GetCollection(idx.Collection)
+
+		opts := options.Index().SetName(idx.Name)
+		if idx.Unique {
+			opts.SetUnique(true)
+		}
+		if idx.Partial != nil {
+			opts.SetPartialFilterExpression(idx.Partial)
+		}
+		if idx.ExpireAfter != nil {
+			opts.SetExpireAfterSeconds(int32(idx.ExpireAfter.Seconds()))
+		}
+		model := mongo.IndexModel{Keys: idx.Keys, Options: opts}
+
+		_, err := collection.Indexes().CreateOne(ctx, model)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Code == codeIndexOptionsConflict || cmdErr.Code == codeIndexKeySpecsConflict) {
+			if _, err := collection.Indexes().DropOne(ctx, idx.Name); err != nil {
+				return fmt.Errorf("index %s.%s: %w", idx.Collection, idx.Name, err)
+			}
+			_, err = collection.Indexes().CreateOne(ctx, model)
+		}
+		if err != nil {
+			return fmt.Errorf("index %s.%s: %w", idx.Collection, idx.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes the register as CSV, one row as each comes from rows,
+// flushing after every row so the response starts before the last row is
+// read. Text cells are guarded against formula injection.
+func WriteCSV(w io.Writer, r *Register, rows RowSource) error {
+	out := csv.NewWriter(w)
+
+	if err := out.Write(r.Header()); err != nil {
+		return err
+	}
+	err := rows(func(row *RegisterRow) error {
+		record := row.Record()
+		for j := range record {
+			record[j] = sheetCell(record[j])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+		out.Flush()
+		return out.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,217 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// ReportInfo is what the PDF report prints besides the register itself.
+type ReportInfo struct {
+	Teacher     string
+	Term        string
+	GeneratedAt time.Time
+}
+
+// Page layout in millimetres on landscape A4.
+const (
+	pdfMargin  = 10.0
+	pdfRowH    = 6.0
+	pdfNameW   = 55.0
+	pdfClassW  = 9.0
+	pdfCountW  = 10.0
+	pdfRateW   = 14.0
+	pdfSignH   = 45.0
+	pdfCountsN = 6
+)
+
+// WritePDF renders the register as a printable report: a header with the
+// course and teacher on every page, the register table, summary figures
+// and signature lines. Long class lists are split into several tables of
+// as many classes as fit across the page, and rows flow onto new pages
+// with the table header repeated. The document is laid out in memory, so
+// the rows are collected first.
+func WritePDF(w io.Writer, r *Register, source RowSource, info ReportInfo) error {
+	var rows []*RegisterRow
+	err := source(func(row *RegisterRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetCreationDate(info.GeneratedAt)
+	pdf.SetTitle(fmt.Sprintf("%s attendance report", r.Course.Code), true)
+	pdf.AliasNbPages("")
+
+	// Core fonts only know cp1252, which covers Spanish names.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pageW, pageH := pdf.GetPageSize()
+	bottom := pageH - pdfMargin - 8
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 7, tr(fmt.Sprintf("%s (%s)", r.Course.Name, r.Course.Code)), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("Teacher: %s    Period: %s", info.Teacher, reportPeriod(r, info))), "", 1, "L", false, 0, "")
+		pdf.Ln(3)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin - 4)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Generated %s", info.GeneratedAt.In(r.Location).Format("2006-01-02 15:04 MST")), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	perTable := int((pageW - 2*pdfMargin - pdfNameW - pdfCountsN*pdfCountW - pdfRateW) / pdfClassW)
+	if perTable < 1 {
+		perTable = 1
+	}
+
+	for start := 0; start == 0 || start < len(r.Columns); start += perTable {
+		end := start + perTable
+		if end > len(r.Columns) {
+			end = len(r.Columns)
+		}
+		if start > 0 {
+			pdf.Ln(4)
+		}
+		if len(r.Columns) > perTable {
+			pdf.SetFont("Helvetica", "B", 9)
+			pdf.CellFormat(0, 5, fmt.Sprintf("Classes %d to %d of %d", start+1, end, len(r.Columns)), "", 1, "L", false, 0, "")
+		}
+
+		tableHeader := func() {
+			pdf.SetFont("Helvetica", "B", 7)
+			pdf.SetFillColor(230, 230, 230)
+			pdf.CellFormat(pdfNameW, pdfRowH, "Student", "1", 0, "L", true, 0, "")
+			for _, c := range r.Columns[start:end] {
+				pdf.CellFormat(pdfClassW, pdfRowH, c.In(r.Location).Format("02/01"), "1", 0, "C", true, 0, "")
+			}
+			for _, title := range []string{"P", "A", "L", "E", "LE", "R"} {
+				pdf.CellFormat(pdfCountW, pdfRowH, title, "1", 0, "C", true, 0, "")
+			}
+			pdf.CellFormat(pdfRateW, pdfRowH, "%", "1", 1, "C", true, 0, "")
+			pdf.SetFont("Helvetica", "", 7)
+		}
+
+		if pdf.GetY()+2*pdfRowH > bottom {
+			pdf.AddPage()
+		}
+		tableHeader()
+
+		for _, row := range rows {
+			if pdf.GetY()+pdfRowH > bottom {
+				pdf.AddPage()
+				tableHeader()
+			}
+
+			pdf.CellFormat(pdfNameW, pdfRowH, tr(fitText(pdf, row.Student.Name, pdfNameW-2)), "1", 0, "L", false, 0, "")
+			for _, cell := range row.Cells[start:end] {
+				pdf.CellFormat(pdfClassW, pdfRowH, cell, "1", 0, "C", false, 0, "")
+			}
+			totals := row.Totals()
+			for _, count := range totals[:pdfCountsN] {
+				pdf.CellFormat(pdfCountW, pdfRowH, count, "1", 0, "C", false, 0, "")
+			}
+			pdf.CellFormat(pdfRateW, pdfRowH, totals[pdfCountsN], "1", 1, "C", false, 0, "")
+		}
+	}
+
+	if pdf.GetY()+pdfSignH > bottom {
+		pdf.AddPage()
+	}
+	writeSummary(pdf, r, rows)
+	writeSignatures(pdf, tr)
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+func writeSummary(pdf *fpdf.Fpdf, r *Register, rows []*RegisterRow) {
+	policy := r.Course.AttendancePolicy()
+
+	var rateSum float64
+	var rated, below int
+	for _, row := range rows {
+		rate := row.Summary.AttendanceRate()
+		if rate == nil {
+			continue
+		}
+		rateSum += *rate
+		rated++
+		if policy.MinAttendancePercent > 0 && *rate < policy.MinAttendancePercent {
+			below++
+		}
+	}
+
+	average := "-"
+	if rated > 0 {
+		average = fmt.Sprintf("%.1f%%", rateSum/float64(rated))
+	}
+
+	pdf.Ln(5)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.CellFormat(0, 5, "Summary", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 8)
+	lines := []string{
+		fmt.Sprintf("Classes: %d    Students: %d    Average attendance: %s", len(r.Columns), len(rows), average),
+		fmt.Sprintf("Students below the required %.0f%%: %d", policy.MinAttendancePercent, below),
+		"P present, A absent, L late, E excused, LE left early, R remote. Attendance % leaves excused classes out.",
+	}
+	for _, line := range lines {
+		pdf.CellFormat(0, 4.5, line, "", 1, "L", false, 0, "")
+	}
+}
+
+func writeSignatures(pdf *fpdf.Fpdf, tr func(string) string) {
+	pdf.Ln(18)
+	pdf.SetFont("Helvetica", "", 8)
+
+	const lineW, gap = 70.0, 20.0
+	y := pdf.GetY()
+	x, _, _, _ := pdf.GetMargins()
+	for i, label := range []string{"Teacher signature", "Registrar signature", "Date"} {
+		lx := x + float64(i)*(lineW+gap)
+		pdf.Line(lx, y, lx+lineW, y)
+		pdf.SetXY(lx, y+1)
+		pdf.CellFormat(lineW, 4, tr(label), "", 0, "C", false, 0, "")
+	}
+	pdf.Ln(5)
+}
+
+// fitText shortens s with an ellipsis until it fits in width.
+func fitText(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// reportPeriod is the term name when given, otherwise the dates of the
+// first and last class.
+func reportPeriod(r *Register, info ReportInfo) string {
+	if info.Term != "" {
+		return info.Term
+	}
+	if len(r.Columns) == 0 {
+		return "no classes"
+	}
+	first := r.Columns[0].In(r.Location).Format("2006-01-02")
+	last := r.Columns[len(r.Columns)-1].In(r.Location).Format("2006-01-02")
+	return first + " to " + last
+}
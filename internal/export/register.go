@@ -0,0 +1,206 @@
+// Package export renders a course's attendance register as CSV, XLSX or
+// PDF for the registrar.
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"money-minder/internal/types"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StatusCodes is the short code printed in a register cell for each status.
+var StatusCodes = map[string]string{
+	types.StatusPresent:   "P",
+	types.StatusAbsent:    "A",
+	types.StatusLate:      "L",
+	types.StatusExcused:   "E",
+	types.StatusLeftEarly: "LE",
+	types.StatusRemote:    "R",
+}
+
+// Register is the layout of a course's attendance register between two
+// dates: one column per class. Its rows, one per enrolled student, come
+// from a RowSource one at a time, so the grid is never held whole.
+type Register struct {
+	Course   *types.Course
+	Location *time.Location
+	Columns  []time.Time
+	index    map[string]int
+}
+
+type RegisterRow struct {
+	Student types.Student
+	// Cells holds the status code of each column, empty when unrecorded.
+	Cells   []string
+	Summary types.AttendanceSummary
+}
+
+// RowSource calls each with the rows of a register in order, stopping at
+// the first error.
+type RowSource func(each func(*RegisterRow) error) error
+
+// totalsHeader names the columns that follow the classes in every row.
+var totalsHeader = []string{"Present", "Absent", "Late", "Excused", "Left early", "Remote", "Attendance %"}
+
+// NewRegister lays out the columns of the register of Course. Classes are
+// ClassSessions plus, for records taken without a session, the dates in
+// unsessioned; both already limited to the range the register covers.
+func NewRegister(Course *types.Course, ClassSessions []*types.ClassSession, unsessioned []time.Time, loc *time.Location) *Register {
+	column := make(map[string]time.Time)
+	for _, s := range ClassSessions {
+		column[s.ID.Hex()] = s.Start
+	}
+	for _, date := range unsessioned {
+		column[dateKey(date)] = date.Truncate(time.Minute)
+	}
+
+	keys := make([]string, 0, len(column))
+	for key := range column {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !column[keys[i]].Equal(column[keys[j]]) {
+			return column[keys[i]].Before(column[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+
+	register := &Register{Course: Course, Location: loc, index: make(map[string]int, len(keys))}
+	for i, key := range keys {
+		register.index[key] = i
+		register.Columns = append(register.Columns, column[key])
+	}
+	return register
+}
+
+// columnKey names the column of a record: its session, or the minute it
+// was dated when it has none.
+func columnKey(a *types.Attendance) string {
+	if !a.SessionID.IsZero() {
+		return a.SessionID.Hex()
+	}
+	return dateKey(a.Date)
+}
+
+func dateKey(t time.Time) string {
+	return "date:" + t.UTC().Truncate(time.Minute).Format(time.RFC3339)
+}
+
+// Row lays out the row of one student from their attendances. Records that
+// fall in none of the register's columns are left out.
+func (r *Register) Row(Student *types.Student, Attendances []*types.Attendance) *RegisterRow {
+	row := &RegisterRow{
+		Student: *Student,
+		Cells:   make([]string, len(r.Columns)),
+		Summary: types.AttendanceSummary{StudentID: Student.ID},
+	}
+
+	var records []*types.Attendance
+	for _, a := range Attendances {
+		i, ok := r.index[columnKey(a)]
+		if !ok {
+			continue
+		}
+		row.Cells[i] = StatusCodes[a.Status]
+		records = append(records, a)
+	}
+	if counts := types.SummarizeAttendances(records); len(counts) > 0 {
+		row.Summary = counts[0]
+	}
+
+	return row
+}
+
+// Rows returns the rows of Students, ordered by ID. records must call its
+// function with the course's attendances ordered by student; they are
+// joined with the students as they arrive, so only one student's records
+// are held at a time.
+func (r *Register) Rows(Students []*types.Student, records func(func(*types.Attendance) error) error) RowSource {
+	sorted := append([]*types.Student(nil), Students...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].ID[:], sorted[j].ID[:]) < 0
+	})
+
+	return func(each func(*RegisterRow) error) error {
+		next := 0
+		var pending []*types.Attendance
+
+		// flush emits the rows of the students ordered before id, or of
+		// every student left when id is nil.
+		flush := func(id *primitive.ObjectID) error {
+			for next < len(sorted) && (id == nil || bytes.Compare(sorted[next].ID[:], id[:]) < 0) {
+				if err := each(r.Row(sorted[next], pending)); err != nil {
+					return err
+				}
+				pending = nil
+				next++
+			}
+			return nil
+		}
+
+		err := records(func(a *types.Attendance) error {
+			if err := flush(&a.StudentID); err != nil {
+				return err
+			}
+			// Records of students no longer enrolled have no row.
+			if next < len(sorted) && sorted[next].ID == a.StudentID {
+				pending = append(pending, a)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return flush(nil)
+	}
+}
+
+// Header returns the column titles of the grid.
+func (r *Register) Header() []string {
+	header := []string{"Student", "Email"}
+	for _, c := range r.Columns {
+		header = append(header, c.In(r.Location).Format("2006-01-02 15:04"))
+	}
+	return append(header, totalsHeader...)
+}
+
+// Totals returns the values of the totals columns of a row.
+func (row *RegisterRow) Totals() []string {
+	rate := ""
+	if r := row.Summary.AttendanceRate(); r != nil {
+		rate = fmt.Sprintf("%.1f", *r)
+	}
+
+	s := row.Summary
+	return []string{
+		fmt.Sprint(s.Present),
+		fmt.Sprint(s.Absent),
+		fmt.Sprint(s.Late),
+		fmt.Sprint(s.Excused),
+		fmt.Sprint(s.LeftEarly),
+		fmt.Sprint(s.Remote),
+		rate,
+	}
+}
+
+// Record returns a row as the strings written under Header.
+func (row *RegisterRow) Record() []string {
+	record := append([]string{row.Student.Name, row.Student.Email}, row.Cells...)
+	return append(record, row.Totals()...)
+}
+
+// sheetCell keeps a text cell from being run as a formula. Names and
+// emails are typed by students, and spreadsheets treat text starting with
+// =, +, -, @, a tab or a carriage return as a formula, so such cells get a
+// leading apostrophe.
+func sheetCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRegisterRows(t *testing.T) {
+	day := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	session := &types.ClassSession{ID: primitive.NewObjectID(), Start: day}
+	other := &types.ClassSession{ID: primitive.NewObjectID(), Start: day.AddDate(0, 0, 7)}
+	register := NewRegister(&types.Course{}, []*types.ClassSession{session, other}, []time.Time{day.Add(time.Hour + 30*time.Second)}, time.UTC)
+
+	ana := &types.Student{ID: primitive.NewObjectID(), Name: "Ana"}
+	dropped := primitive.NewObjectID()
+	bea := &types.Student{ID: primitive.NewObjectID(), Name: "Bea"}
+	carla := &types.Student{ID: primitive.NewObjectID(), Name: "Carla"}
+
+	// Ordered by student, as the repository yields them.
+	records := []*types.Attendance{
+		{StudentID: ana.ID, SessionID: session.ID, Status: types.StatusPresent},
+		{StudentID: ana.ID, Date: day.Add(time.Hour), Status: types.StatusLate},
+		{StudentID: dropped, SessionID: session.ID, Status: types.StatusAbsent},
+		{StudentID: carla.ID, SessionID: other.ID, Status: types.StatusAbsent},
+		// A session outside the register has no column.
+		{StudentID: carla.ID, SessionID: primitive.NewObjectID(), Status: types.StatusPresent},
+	}
+	source := register.Rows([]*types.Student{carla, bea, ana}, func(fn func(*types.Attendance) error) error {
+		for _, a := range records {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var got []string
+	err := source(func(row *RegisterRow) error {
+		got = append(got, row.Student.Name+":"+strings.Join(row.Cells, ",")+":"+row.Totals()[0])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Ana:P,L,:1", "Bea:,,:0", "Carla:,,A:0"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got rows %v, want %v", got, want)
+	}
+
+	stop := errors.New("stop")
+	if err := source(func(*RegisterRow) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("got %v, want the error returned for the first row", err)
+	}
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The fixed parts of a one-sheet workbook. Cells use inline strings, so no
+// shared string table is needed and rows can be written as they come.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Attendance" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+)
+
+// WriteXLSX writes the register as a single-sheet Excel workbook. The zip
+// archive is written to w one row as each comes from rows.
+func WriteXLSX(w io.Writer, r *Register, rows RowSource) error {
+	z := zip.NewWriter(w)
+
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sheet := bufio.NewWriter(f)
+
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	// Totals are numbers, everything else is text.
+	firstTotal := 2 + len(r.Columns)
+
+	writeRow(sheet, 1, r.Header(), -1)
+	n := 1
+	err = rows(func(row *RegisterRow) error {
+		n++
+		writeRow(sheet, n, row.Record(), firstTotal)
+		return sheet.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	sheet.WriteString(`</sheetData></worksheet>`)
+	if err := sheet.Flush(); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+// writeRow writes one sheet row. Cells from numericFrom on that hold a
+// number are written as numbers; a negative numericFrom writes only text.
+// Text cells are guarded against formula injection.
+func writeRow(w *bufio.Writer, row int, cells []string, numericFrom int) {
+	n := strconv.Itoa(row)
+	w.WriteString(`<row r="` + n + `">`)
+
+	for i, value := range cells {
+		ref := columnName(i) + n
+		if value == "" {
+			continue
+		}
+		if numericFrom >= 0 && i >= numericFrom {
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				w.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+				continue
+			}
+		}
+		w.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(w, []byte(sheetCell(value)))
+		w.WriteString(`</t></is></c>`)
+	}
+
+	w.WriteString(`</row>`)
+}
+
+// columnName turns a zero-based column index into its letters: A, B, ...,
+// Z, AA, AB, ...
+func columnName(i int) string {
+	var b strings.Builder
+	for i++; i > 0; i = (i - 1) / 26 {
+		b.WriteByte(byte('A' + (i-1)%26))
+	}
+	name := []byte(b.String())
+	for l, r := 0, len(name)-1; l < r; l, r = l+1, r-1 {
+		name[l], name[r] = name[r], name[l]
+	}
+	return string(name)
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"money-minder/internal/auth"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"money-minder/internal/validate"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// account is a student, teacher or admin. Each role keeps its accounts in
+// its own collection.
+type account struct {
+	Role     string
+	ID       primitive.ObjectID
+	Name     string
+	Email    string
+	Password string
+	Status   string
+}
+
+func (a *account) response() UserResponse {
+	return UserResponse{ID: a.ID, Name: a.Name, Email: a.Email, Role: a.Role, Status: types.AccountStatus(a.Status)}
+}
+
+func studentAccount(s *types.Student) *account {
+	return &account{Role: auth.RoleStudent, ID: s.ID, Name: s.Name, Email: s.Email, Password: s.Password, Status: s.Status}
+}
+
+func teacherAccount(t *types.Teacher) *account {
+	return &account{Role: auth.RoleTeacher, ID: t.ID, Name: t.Name, Email: t.Email, Password: t.Password, Status: t.Status}
+}
+
+func adminAccount(a *types.Admin) *account {
+	return &account{Role: auth.RoleAdmin, ID: a.ID, Name: a.Name, Email: a.Email, Password: a.Password, Status: a.Status}
+}
+
+// findAccount looks id up among students, teachers and admins.
+func (h *Handler) findAccount(id string) (*account, error) {
+	Student, err := h.students.FindStudentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if Student != nil {
+		return studentAccount(Student), nil
+	}
+
+	Teacher, err := h.teachers.FindTeacherByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if Teacher != nil {
+		return teacherAccount(Teacher), nil
+	}
+
+	Admin, err := h.admins.FindAdminByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if Admin != nil {
+		return adminAccount(Admin), nil
+	}
+
+	return nil, &repositories.NotFoundError{Resource: "User"}
+}
+
+func (h *Handler) setAccountStatus(a *account, status string) error {
+	switch a.Role {
+	case auth.RoleStudent:
+		return h.students.SetStatus(a.ID.Hex(), status)
+	case auth.RoleTeacher:
+		return h.teachers.SetStatus(a.ID.Hex(), status)
+	}
+	return h.admins.SetStatus(a.ID.Hex(), status)
+}
+
+func (h *Handler) setAccountPassword(a *account, hash string) error {
+	switch a.Role {
+	case auth.RoleStudent:
+		return h.students.UpdatePassword(a.ID.Hex(), hash)
+	case auth.RoleTeacher:
+		return h.teachers.UpdatePassword(a.ID.Hex(), hash)
+	}
+	return h.admins.UpdatePassword(a.ID.Hex(), hash)
+}
+
+// targetAccount finds the account in the path and refuses to act on the
+// caller's own one when self is false.
+func (h *Handler) targetAccount(r *http.Request, self bool, msg string) (*account, error) {
+	claims, err := callerClaims(r)
+	if err != nil {
+		return nil, err
+	}
+
+	Account, err := h.findAccount(r.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+	if !self && Account.ID == claims.ID {
+		return nil, APIError{Status: http.StatusConflict, Msg: msg}
+	}
+	return Account, nil
+}
+
+// GetAllUsers pages through the accounts of one role, searching their name
+// and email and filtering by account status.
+func (h *Handler) GetAllUsers(w http.ResponseWriter, r *http.Request) error {
+
+	role := r.URL.Query().Get("role")
+	v := validate.Struct(struct {
+		Role string `json:"role" validate:"required,oneof=student teacher admin"`
+	}{role})
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	var sorts []string
+	switch role {
+	case auth.RoleStudent:
+		sorts = repositories.StudentSorts.Names()
+	case auth.RoleTeacher:
+		sorts = repositories.TeacherSorts.Names()
+	default:
+		sorts = repositories.AdminSorts.Names()
+	}
+
+	q, err := listQuery(r, "name", sorts, types.ValidAccountStatus)
+	if err != nil {
+		return err
+	}
+
+	var page repositories.Page[UserResponse]
+	switch role {
+	case auth.RoleStudent:
+		Students, err := h.students.ListStudents(nil, q)
+		if err != nil {
+			return err
+		}
+		page = mapPage(Students, func(s *types.Student) UserResponse { return studentAccount(s).response() })
+	case auth.RoleTeacher:
+		Teachers, err := h.teachers.ListTeachers(q)
+		if err != nil {
+			return err
+		}
+		page = mapPage(Teachers, func(t *types.Teacher) UserResponse { return teacherAccount(t).response() })
+	default:
+		Admins, err := h.admins.ListAdmins(q)
+		if err != nil {
+			return err
+		}
+		page = mapPage(Admins, func(a *types.Admin) UserResponse { return adminAccount(a).response() })
+	}
+
+	return WriteJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) GetUserByID(w http.ResponseWriter, r *http.Request) error {
+
+	Account, err := h.findAccount(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, Account.response())
+}
+
+// DisableUser keeps the account from logging in and ends its sessions.
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) error {
+
+	Account, err := h.targetAccount(r, false, "You cannot disable your own account")
+	if err != nil {
+		return err
+	}
+
+	if err := h.setAccountStatus(Account, types.AccountDisabled); err != nil {
+		return err
+	}
+	if err := h.sessions.RevokeUserSessions(Account.ID); err != nil {
+		return err
+	}
+
+	Account.Status = types.AccountDisabled
+	return WriteJSON(w, http.StatusOK, Account.response())
+}
+
+// EnableUser lets a disabled account log in again.
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) error {
+
+	Account, err := h.targetAccount(r, true, "")
+	if err != nil {
+		return err
+	}
+	if types.AccountStatus(Account.Status) == types.AccountPending {
+		return APIError{Status: http.StatusConflict, Msg: "Pending accounts are approved, not enabled"}
+	}
+
+	if err := h.setAccountStatus(Account, types.AccountActive); err != nil {
+		return err
+	}
+
+	Account.Status = types.AccountActive
+	return WriteJSON(w, http.StatusOK, Account.response())
+}
+
+// ApproveUser activates a teacher who registered themselves.
+func (h *Handler) ApproveUser(w http.ResponseWriter, r *http.Request) error {
+
+	Account, err := h.targetAccount(r, true, "")
+	if err != nil {
+		return err
+	}
+	if types.AccountStatus(Account.Status) != types.AccountPending {
+		return APIError{Status: http.StatusConflict, Msg: "Account is not awaiting approval"}
+	}
+
+	if err := h.setAccountStatus(Account, types.AccountActive); err != nil {
+		return err
+	}
+
+	Account.Status = types.AccountActive
+	return WriteJSON(w, http.StatusOK, Account.response())
+}
+
+// UpdateUserRole moves an account to the collection of another role,
+// keeping its ID, email and password, and ends its sessions so the new
+// role applies from the next login. Accounts still taking, teaching or
+// owning courses must leave them first.
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) error {
+
+	roleRequest := &UserRoleRequest{}
+	if err := decodeRequest(w, r, roleRequest); err != nil {
+		return err
+	}
+
+	Account, err := h.targetAccount(r, false, "You cannot change your own role")
+	if err != nil {
+		return err
+	}
+	if Account.Role == roleRequest.Role {
+		return WriteJSON(w, http.StatusOK, Account.response())
+	}
+
+	if err := h.checkNoCourses(Account); err != nil {
+		return err
+	}
+
+	switch roleRequest.Role {
+	case auth.RoleStudent:
+		_, err = h.students.InsertStudent(&types.Student{ID: Account.ID, Name: Account.Name, Email: Account.Email, Password: Account.Password, Status: Account.Status})
+	case auth.RoleTeacher:
+		_, err = h.teachers.InsertTeacher(&types.Teacher{ID: Account.ID, Name: Account.Name, Email: Account.Email, Password: Account.Password, Status: Account.Status})
+	default:
+		_, err = h.admins.InsertAdmin(&types.Admin{ID: Account.ID, Name: Account.Name, Email: Account.Email, Password: Account.Password, Status: Account.Status})
+	}
+	if err != nil {
+		return err
+	}
+
+	// The account must never be left under both roles: when the old copy
+	// cannot be removed, the new one goes too.
+	result, err := h.deleteAccount(Account.Role, Account.ID)
+	if err == nil && result.DeletedCount == 0 {
+		err = &repositories.NotFoundError{Resource: "User"}
+	}
+	if err != nil {
+		if _, rollbackErr := h.deleteAccount(roleRequest.Role, Account.ID); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("rolling back role change of %s: %w", Account.ID.Hex(), rollbackErr))
+		}
+		return err
+	}
+
+	if err := h.sessions.RevokeUserSessions(Account.ID); err != nil {
+		return err
+	}
+
+	Account.Role = roleRequest.Role
+	return WriteJSON(w, http.StatusOK, Account.response())
+}
+
+// deleteAccount removes the account id from the collection of role.
+func (h *Handler) deleteAccount(role string, id primitive.ObjectID) (*repositories.DeleteResult, error) {
+	switch role {
+	case auth.RoleStudent:
+		return h.students.DeleteStudentByID(id.Hex())
+	case auth.RoleTeacher:
+		return h.teachers.DeleteTeacherByID(id.Hex())
+	}
+	return h.admins.DeleteAdminByID(id.Hex())
+}
+
+// checkNoCourses refuses to change the role of an account that courses
+// still refer to.
+func (h *Handler) checkNoCourses(a *account) error {
+	if a.Role == auth.RoleAdmin {
+		return nil
+	}
+
+	Enrollments, err := h.enrollments.GetEnrollmentsByUserID(a.ID.Hex(), a.Role)
+	if err != nil {
+		return err
+	}
+	for _, e := range Enrollments {
+		if e.Status == types.EnrollmentActive {
+			return APIError{Status: http.StatusConflict, Msg: "User is still enrolled in courses"}
+		}
+	}
+
+	if a.Role == auth.RoleTeacher {
+		Courses, err := h.courses.GetCoursesByTeacherID(a.ID.Hex())
+		if err != nil {
+			return err
+		}
+		if len(Courses) > 0 {
+			return APIError{Status: http.StatusConflict, Msg: "User still owns courses"}
+		}
+	}
+	return nil
+}
+
+// ResetUserPassword sets the password sent or, without one, a temporary
+// password returned once in the response. The account's sessions end.
+func (h *Handler) ResetUserPassword(w http.ResponseWriter, r *http.Request) error {
+
+	passwordRequest := &UserPasswordRequest{}
+	if r.ContentLength != 0 {
+		if err := decodeRequest(w, r, passwordRequest); err != nil {
+			return err
+		}
+	}
+
+	Account, err := h.targetAccount(r, true, "")
+	if err != nil {
+		return err
+	}
+
+	password := passwordRequest.Password
+	generated := password == ""
+	if generated {
+		if password, err = temporaryPassword(); err != nil {
+			return APIError{Status: http.StatusInternalServerError, Msg: "Error generating password"}
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return APIError{Status: http.StatusInternalServerError, Msg: "Error hashing password"}
+	}
+
+	if err := h.setAccountPassword(Account, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := h.sessions.RevokeUserSessions(Account.ID); err != nil {
+		return err
+	}
+
+	response := UserPasswordResponse{UserResponse: Account.response()}
+	if generated {
+		response.TemporaryPassword = password
+	}
+
+	return WriteJSON(w, http.StatusOK, response)
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=student teacher admin"`
+}
+
+// UserPasswordRequest sets a password; an empty one asks for a temporary
+// password instead.
+type UserPasswordRequest struct {
+	Password string `json:"password" validate:"min=8,max=72"`
+}
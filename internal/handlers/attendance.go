@@ -1,122 +1,244 @@
 package handlers
 
 import (
-	"encoding/json"
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
 	"money-minder/internal/types"
+	"money-minder/internal/validate"
 	"net/http"
+	"time"
 )
 
-func CreateAttendance(w http.ResponseWriter, r *http.Request) error {
-	Attendance := &types.Attendance{}
-	derr := json.NewDecoder(r.Body).Decode(Attendance)
+func (h *Handler) CreateAttendance(w http.ResponseWriter, r *http.Request) error {
+	req := &AttendanceRequest{}
+	if err := decodeJSON(w, r, req); err != nil {
+		return err
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Could not create Attendance, verify that the values are formatted correctly",
-		}
+	v := validate.Struct(req)
+	if req.CourseID == "" && req.SessionID == "" {
+		v.Add("courseId", "is required without a sessionId")
+	}
+	if req.SessionID == "" && req.Date.IsZero() {
+		v.Add("date", "is required without a sessionId")
+	}
+	if req.CheckInAt != nil && req.CheckOutAt != nil && req.CheckOutAt.Before(*req.CheckInAt) {
+		v.Add("checkOutAt", "must be after checkInAt")
+	}
+	if err := checkReference(v, "studentId", req.StudentID, h.studentExists); err != nil {
+		return err
+	}
+	if err := checkReference(v, "courseId", req.CourseID, h.courseExists); err != nil {
+		return err
+	}
+	if err := checkReference(v, "sessionId", req.SessionID, h.classSessionExists); err != nil {
+		return err
+	}
+	if err := v.Err(); err != nil {
+		return err
 	}
 
-	result, err := attendanceRepository.InsertAttendance(Attendance)
+	Attendance, err := req.attendance()
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
+		return err
+	}
+
+	// Attendance taken for a class session belongs to that session's course.
+	if !Attendance.SessionID.IsZero() {
+		ClassSession, err := h.findClassSession(req.SessionID)
+		if err != nil {
+			return err
+		}
+		if !Attendance.CourseID.IsZero() && Attendance.CourseID != ClassSession.CourseID {
+			return &repositories.ValidationError{Fields: []repositories.FieldError{
+				{Field: "sessionId", Message: "does not belong to the given course"},
+			}}
+		}
+		Attendance.CourseID = ClassSession.CourseID
+		if Attendance.Date.IsZero() {
+			Attendance.Date = ClassSession.Start
 		}
 	}
 
+	loc := time.UTC
+	if !Attendance.CourseID.IsZero() {
+		Course, err := h.courses.FindCourseByID(Attendance.CourseID.Hex())
+		if err != nil {
+			return err
+		}
+		if Course != nil {
+			loc = schedule.Location(Course.Schedules)
+		}
+	}
+	Attendance.SetDay(loc)
+
+	result, err := h.attendances.InsertAttendance(Attendance)
+	if err != nil {
+		return err
+	}
+
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func DeleteAttendance(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) DeleteAttendance(w http.ResponseWriter, r *http.Request) error {
 
 	AttendanceId := r.PathValue("id")
 
-	result, err := attendanceRepository.DeleteAttendance(AttendanceId)
+	result, err := h.attendances.DeleteAttendance(AttendanceId)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func UpdateAttendance(w http.ResponseWriter, r *http.Request) error {
+// UpdateAttendance corrects the status of an attendance record. Fields left
+// out of the request keep their value; isPresent is still accepted and maps
+// to present or absent.
+func (h *Handler) UpdateAttendance(w http.ResponseWriter, r *http.Request) error {
+
+	AttendanceId := r.PathValue("id")
+
+	updateRequest := &UpdateAttendanceRequest{}
+	if err := decodeRequest(w, r, updateRequest); err != nil {
+		return err
+	}
 
-	userId := r.PathValue("id")
+	Attendance, err := h.attendances.FindAttendanceByID(AttendanceId)
+	if err != nil {
+		return err
+	}
+	if Attendance == nil {
+		return &repositories.NotFoundError{Resource: "Attendance"}
+	}
 
-	updateUsr := &UpdateAttendanceRequest{}
-	derr := json.NewDecoder(r.Body).Decode(updateUsr)
+	switch {
+	case updateRequest.Status != "":
+		Attendance.SetStatus(updateRequest.Status)
+	case updateRequest.IsPresent != nil && *updateRequest.IsPresent:
+		Attendance.SetStatus(types.StatusPresent)
+	case updateRequest.IsPresent != nil:
+		Attendance.SetStatus(types.StatusAbsent)
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt update attendance isPresent value, verify that the values are formatted correctly",
+	if updateRequest.MinutesLate != nil {
+		if Attendance.Status != types.StatusLate {
+			return &repositories.ValidationError{Fields: []repositories.FieldError{
+				{Field: "minutesLate", Message: "only applies to late attendances"},
+			}}
 		}
+		Attendance.MinutesLate = *updateRequest.MinutesLate
+	}
+	if updateRequest.CheckInAt != nil {
+		Attendance.CheckInAt = updateRequest.CheckInAt
+	}
+	if updateRequest.CheckOutAt != nil {
+		Attendance.CheckOutAt = updateRequest.CheckOutAt
+	}
+	if Attendance.CheckInAt != nil && Attendance.CheckOutAt != nil && Attendance.CheckOutAt.Before(*Attendance.CheckInAt) {
+		return APIError{Status: http.StatusBadRequest, Msg: "checkOutAt must be after checkInAt"}
+	}
+	if updateRequest.Note != nil {
+		Attendance.Note = *updateRequest.Note
 	}
 
-	err := attendanceRepository.UpdatePresent(userId, updateUsr.IsPresent)
+	err = h.attendances.UpdateStatus(AttendanceId, Attendance)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, "Attendance's isPresent value updated succesfully")
+	return WriteJSON(w, http.StatusOK, "Attendance updated succesfully")
 }
 
-func GetAttendanceByID(w http.ResponseWriter, r *http.Request) error {
+// GetAttendanceByID returns one attendance record.
+func (h *Handler) GetAttendanceByID(w http.ResponseWriter, r *http.Request) error {
 
 	AttendanceId := r.PathValue("id")
 
-	Attendance, err := attendanceRepository.FindAttendanceByID(AttendanceId)
+	Attendance, err := h.attendances.FindAttendanceByID(AttendanceId)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
+	}
+	if Attendance == nil {
+		return &repositories.NotFoundError{Resource: "Attendance"}
 	}
 
 	return WriteJSON(w, http.StatusOK, Attendance)
 }
 
-func GetAllAttendancesByCourseID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetAllAttendancesByCourseID(w http.ResponseWriter, r *http.Request) error {
 
 	CourseID := r.PathValue("id")
 
-	cards, err := attendanceRepository.GetAttendancesByCourseID(CourseID)
+	q, err := listQuery(r, "date", repositories.AttendanceSorts.Names(), types.ValidStatus)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.attendances.ListAttendancesByCourseID(CourseID, q)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, cards)
+	return WriteJSON(w, http.StatusOK, page)
 }
 
-func GetAllAttendancesByStudentID(w http.ResponseWriter, r *http.Request) error {
+// GetCourseAttendanceSummary counts every student's attendance in a course,
+// with lates converted into absences by the course policy.
+func (h *Handler) GetCourseAttendanceSummary(w http.ResponseWriter, r *http.Request) error {
+
+	CourseID := r.PathValue("id")
+
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	summaries, err := h.attendances.CountAttendancesByStudent(CourseID)
+	if err != nil {
+		return err
+	}
+
+	policy := Course.AttendancePolicy()
+	for i := range summaries {
+		summaries[i].ApplyPolicy(policy)
+	}
+	if summaries == nil {
+		summaries = []types.AttendanceSummary{}
+	}
+
+	return WriteJSON(w, http.StatusOK, summaries)
+}
+
+func (h *Handler) GetAllAttendancesByStudentID(w http.ResponseWriter, r *http.Request) error {
 
 	StudentID := r.PathValue("id")
 
-	attendances, err := attendanceRepository.GetAttendancesByStudentID(StudentID)
+	q, err := listQuery(r, "date", repositories.AttendanceSorts.Names(), types.ValidStatus)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.attendances.ListAttendancesByStudentID(StudentID, q)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, attendances)
+	return WriteJSON(w, http.StatusOK, page)
 }
 
 type UpdateAttendanceRequest struct {
-	IsPresent bool `json:"isPresent" bson:"present"`
+	IsPresent   *bool      `json:"isPresent" bson:"present"`
+	Status      string     `json:"status" bson:"status" validate:"oneof=present absent late excused left-early remote"`
+	MinutesLate *int       `json:"minutesLate" bson:"minutes_late" validate:"min=0"`
+	CheckInAt   *time.Time `json:"checkInAt" bson:"check_in_at"`
+	CheckOutAt  *time.Time `json:"checkOutAt" bson:"check_out_at"`
+	Note        *string    `json:"note" bson:"note" validate:"max=500"`
 }
@@ -1,37 +1,30 @@
 package handlers
 
 import (
-	"encoding/json"
+	"log/slog"
+	"money-minder/internal/auth"
+	"money-minder/internal/repositories"
 	"money-minder/internal/types"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	jwtKey = []byte(os.Getenv("JWT_SECRET_KEY"))
-)
-
-type Claims struct {
-	ID    interface{} `json:"id"`
-	Email string      `json:"email"`
-	Role  string      `json:"role"`
-	jwt.RegisteredClaims
-}
-
-func Register(w http.ResponseWriter, r *http.Request) error {
+// Register creates a student or teacher account. Teachers wait as pending
+// until an admin approves them, so nobody can make themselves a teacher.
+// Admins are only created by the bootstrap or by an admin changing a role.
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) error {
 	var registerRequest struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		Role     string `json:"role"` // "student" or "teacher"
+		Name     string `json:"name" validate:"required,max=100"`
+		Email    string `json:"email" validate:"required,email,max=254"`
+		Password string `json:"password" validate:"required,min=8,max=72"`
+		Role     string `json:"role" validate:"required,oneof=student teacher"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&registerRequest); err != nil {
-		return APIError{Status: http.StatusBadRequest, Msg: "Invalid request body"}
+	if err := decodeRequest(w, r, &registerRequest); err != nil {
+		return err
 	}
 
 	// Hash the password
@@ -40,65 +33,78 @@ func Register(w http.ResponseWriter, r *http.Request) error {
 		return APIError{Status: http.StatusInternalServerError, Msg: "Error hashing password"}
 	}
 
-	var result interface{}
+	var result *repositories.InsertResult
 
 	switch registerRequest.Role {
-	case "student":
+	case auth.RoleStudent:
 		student := &types.Student{
 			Name:     registerRequest.Name,
 			Email:    registerRequest.Email,
 			Password: string(hashedPassword),
 		}
-		result, err = studentRepository.InsertStudent(student)
-	case "teacher":
+		result, err = h.students.InsertStudent(student)
+	case auth.RoleTeacher:
 		teacher := &types.Teacher{
 			Name:     registerRequest.Name,
 			Email:    registerRequest.Email,
 			Password: string(hashedPassword),
+			Status:   types.AccountPending,
 		}
-		result, err = teacherRepository.InsertTeacher(teacher)
+		result, err = h.teachers.InsertTeacher(teacher)
 	default:
 		return APIError{Status: http.StatusBadRequest, Msg: "Invalid role"}
 	}
 
 	if err != nil {
-		return APIError{Status: http.StatusInternalServerError, Msg: err.Error()}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusCreated, result)
 }
 
-func Login(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) error {
 	var loginRequest struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		Role     string `json:"role"` // "student" or "teacher"
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
+		Role     string `json:"role" validate:"required,oneof=student teacher admin"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&loginRequest); err != nil {
-		return APIError{Status: http.StatusBadRequest, Msg: "Invalid request body"}
+	if err := decodeRequest(w, r, &loginRequest); err != nil {
+		return err
 	}
+	loginRequest.Email = types.NormalizeEmail(loginRequest.Email)
 
 	var (
-		id       interface{}
+		id       primitive.ObjectID
 		password string
+		status   string
 	)
 
 	switch loginRequest.Role {
-	case "student":
-		student, err := studentRepository.FindStudentByEmail(loginRequest.Email)
-		if err != nil {
+	case auth.RoleStudent:
+		student, err := h.students.FindStudentByEmail(loginRequest.Email)
+		if err != nil || student == nil {
 			return APIError{Status: http.StatusUnauthorized, Msg: "Invalid credentials"}
 		}
 		id = student.ID
 		password = student.Password
-	case "teacher":
-		teacher, err := teacherRepository.FindTeacherByEmail(loginRequest.Email)
-		if err != nil {
+		status = student.Status
+	case auth.RoleTeacher:
+		teacher, err := h.teachers.FindTeacherByEmail(loginRequest.Email)
+		if err != nil || teacher == nil {
 			return APIError{Status: http.StatusUnauthorized, Msg: "Invalid credentials"}
 		}
 		id = teacher.ID
 		password = teacher.Password
+		status = teacher.Status
+	case auth.RoleAdmin:
+		admin, err := h.admins.FindAdminByEmail(loginRequest.Email)
+		if err != nil || admin == nil {
+			return APIError{Status: http.StatusUnauthorized, Msg: "Invalid credentials"}
+		}
+		id = admin.ID
+		password = admin.Password
+		status = admin.Status
 	default:
 		return APIError{Status: http.StatusBadRequest, Msg: "Invalid role"}
 	}
@@ -107,28 +113,130 @@ func Login(w http.ResponseWriter, r *http.Request) error {
 		return APIError{Status: http.StatusUnauthorized, Msg: "Invalid credentials"}
 	}
 
-	// Create claims with expiry
-	claims := &Claims{
-		ID:    id,
-		Email: loginRequest.Email,
-		Role:  loginRequest.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	// Only tell the account is not active to whoever knows its password.
+	switch types.AccountStatus(status) {
+	case types.AccountPending:
+		return APIError{Status: http.StatusForbidden, Msg: "Account is awaiting approval"}
+	case types.AccountDisabled:
+		return APIError{Status: http.StatusForbidden, Msg: "Account is disabled"}
+	}
+
+	session, err := h.startSession(id, loginRequest.Email, loginRequest.Role, primitive.NewObjectID())
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, session)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Presenting a token that was already rotated revokes its whole family,
+// since it means the token was stolen or replayed.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	var refreshRequest struct {
+		RefreshToken string `json:"refreshToken" validate:"required"`
+	}
+
+	if err := decodeRequest(w, r, &refreshRequest); err != nil {
+		return err
+	}
+
+	session, err := h.sessions.FindSessionByTokenHash(auth.HashRefreshToken(refreshRequest.RefreshToken))
+	if err != nil {
+		return err
+	}
+	if session == nil || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return APIError{Status: http.StatusUnauthorized, Msg: "Invalid refresh token"}
+	}
+
+	rotated, err := h.sessions.MarkSessionUsed(session.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !rotated {
+		if err := h.sessions.RevokeFamily(session.FamilyID); err != nil {
+			return err
+		}
+		slog.Warn("Refresh token reuse detected", "family", session.FamilyID.Hex(), "user", session.UserID.Hex())
+		return APIError{Status: http.StatusUnauthorized, Msg: "Invalid refresh token"}
+	}
+
+	next, err := h.startSession(session.UserID, session.Email, session.Role, session.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, next)
+}
+
+// Logout revokes the session family the caller's access token belongs to.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if err := h.sessions.RevokeFamily(claims.SessionID); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Logged out sucessfully.")
+}
+
+// LogoutAll revokes every session of the caller, on every device.
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) error {
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.sessions.RevokeUserSessions(claims.ID); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Logged out of all devices sucessfully.")
+}
+
+type tokenResponse struct {
+	ID               primitive.ObjectID `json:"id"`
+	Token            string             `json:"token"`
+	ExpiresAt        time.Time          `json:"expiresAt"`
+	RefreshToken     string             `json:"refreshToken"`
+	RefreshExpiresAt time.Time          `json:"refreshExpiresAt"`
+}
+
+// startSession stores a new refresh token in familyID and issues the access
+// token that goes with it.
+func (h *Handler) startSession(userID primitive.ObjectID, email string, role string, familyID primitive.ObjectID) (*tokenResponse, error) {
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, APIError{Status: http.StatusInternalServerError, Msg: "Error generating token"}
+	}
+
+	now := time.Now()
+	session := &types.Session{
+		FamilyID:  familyID,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.tokens.RefreshTTL()),
+	}
+	if _, err := h.sessions.InsertSession(session); err != nil {
+		return nil, err
+	}
 
-	// Sign the token
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, expiresAt, err := h.tokens.Issue(userID, email, role, familyID)
 	if err != nil {
-		return APIError{Status: http.StatusInternalServerError, Msg: "Error generating token"}
+		return nil, APIError{Status: http.StatusInternalServerError, Msg: "Error generating token"}
 	}
 
-	return WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"token": tokenString,
-		"id":    id,
-	})
+	return &tokenResponse{
+		ID:               userID,
+		Token:            tokenString,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
 }
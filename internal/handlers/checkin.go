@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"money-minder/internal/checkin"
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
+	"money-minder/internal/types"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultCheckInDuration = 10 * time.Minute
+	maxCheckInDuration     = 4 * time.Hour
+	defaultRotateSeconds   = 30
+	minRotateSeconds       = 5
+	maxRotateSeconds       = 300
+	defaultCodeTTL         = 2 * time.Minute
+	maxCodeFailures        = 5
+	codeLockout            = 5 * time.Minute
+)
+
+// OpenCheckIn starts a check-in window on a course. Without a sessionId a new
+// class session covering the window is opened as well.
+func (h *Handler) OpenCheckIn(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	openRequest := &OpenCheckInRequest{}
+	if err := decodeRequest(w, r, openRequest); err != nil {
+		return err
+	}
+
+	duration := defaultCheckInDuration
+	if openRequest.DurationSeconds != 0 {
+		duration = time.Duration(openRequest.DurationSeconds) * time.Second
+	}
+	rotate := defaultRotateSeconds
+	if openRequest.RotateSeconds != 0 {
+		rotate = openRequest.RotateSeconds
+	}
+	if duration <= 0 || duration > maxCheckInDuration || rotate < minRotateSeconds || rotate > maxRotateSeconds {
+		return APIError{
+			Status: http.StatusBadRequest,
+			Msg:    "Check-in duration must be at most 4 hours and rotation between 5 and 300 seconds",
+		}
+	}
+
+	Course, err := h.courses.FindCourseByID(CourseId)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	now := time.Now()
+	Window := &types.CheckInWindow{
+		CourseID:      Course.ID,
+		OpenedBy:      claims.ID,
+		OpensAt:       now,
+		ClosesAt:      now.Add(duration),
+		RotateSeconds: rotate,
+		Key:           make([]byte, 32),
+	}
+	if _, err := rand.Read(Window.Key); err != nil {
+		return APIError{
+			Status: http.StatusInternalServerError,
+			Msg:    "Error generating check-in key",
+		}
+	}
+
+	if openRequest.SessionId != "" {
+		ClassSession, err := h.findClassSession(openRequest.SessionId)
+		if err != nil {
+			return err
+		}
+		if ClassSession.CourseID != Course.ID || ClassSession.Status != types.ClassSessionOpen {
+			return APIError{
+				Status: http.StatusBadRequest,
+				Msg:    "Class Session must be open and belong to the course",
+			}
+		}
+		Window.SessionID = ClassSession.ID
+	} else {
+		ClassSession := &types.ClassSession{
+			CourseID: Course.ID,
+			Start:    now,
+			End:      Window.ClosesAt,
+			Status:   types.ClassSessionOpen,
+		}
+		// A check-in opened during a scheduled class belongs to it, so the
+		// session lasts as long as the class and not just the window.
+		if occurrence, ok := schedule.Overlapping(Course.Schedules, now, Window.ClosesAt); ok {
+			ClassSession.Room = occurrence.Room
+			if occurrence.End.After(ClassSession.End) {
+				ClassSession.End = occurrence.End
+			}
+		}
+
+		result, err := h.classSessions.InsertClassSession(ClassSession)
+		if err != nil {
+			return err
+		}
+		Window.SessionID = result.InsertedID
+	}
+
+	result, err := h.checkInWindows.InsertCheckInWindow(Window)
+	if err != nil {
+		return err
+	}
+	Window.ID = result.InsertedID
+
+	return WriteJSON(w, http.StatusOK, newCheckInState(Window, now))
+}
+
+// GetCheckInToken returns the token currently displayed for a window, for
+// clients that render their own QR code.
+func (h *Handler) GetCheckInToken(w http.ResponseWriter, r *http.Request) error {
+
+	Window, err := h.findOpenCheckInWindow(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, newCheckInState(Window, time.Now()))
+}
+
+// GetCheckInQR renders the current token as a QR code. format is png
+// (default) or svg; size sets the PNG width in pixels.
+func (h *Handler) GetCheckInQR(w http.ResponseWriter, r *http.Request) error {
+
+	Window, err := h.findOpenCheckInWindow(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	token, rotatesAt := checkin.Token(Window, time.Now())
+
+	var (
+		image       []byte
+		contentType string
+	)
+	switch r.URL.Query().Get("format") {
+	case "", "png":
+		size := 512
+		if s := r.URL.Query().Get("size"); s != "" {
+			size, err = strconv.Atoi(s)
+			if err != nil || size < 128 || size > 2048 {
+				return APIError{Status: http.StatusBadRequest, Msg: "size must be between 128 and 2048"}
+			}
+		}
+		image, err = checkin.PNG(token, size)
+		contentType = "image/png"
+	case "svg":
+		image, err = checkin.SVG(token)
+		contentType = "image/svg+xml"
+	default:
+		return APIError{Status: http.StatusBadRequest, Msg: "format must be png or svg"}
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Token-Rotates-At", rotatesAt.UTC().Format(time.RFC3339))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(image)
+	return err
+}
+
+func (h *Handler) CloseCheckIn(w http.ResponseWriter, r *http.Request) error {
+
+	WindowId := r.PathValue("id")
+
+	if err := h.checkInWindows.CloseCheckInWindow(WindowId, time.Now()); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Check-in closed sucessfully.")
+}
+
+// CheckIn records the calling student as present for the session of the
+// scanned token's window.
+func (h *Handler) CheckIn(w http.ResponseWriter, r *http.Request) error {
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	checkInRequest := &CheckInRequest{}
+	if err := decodeRequest(w, r, checkInRequest); err != nil {
+		return err
+	}
+
+	WindowID, err := checkin.WindowID(checkInRequest.Token)
+	if err != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+
+	Window, err := h.checkInWindows.FindCheckInWindowByID(WindowID.Hex())
+	if err != nil {
+		return err
+	}
+	if Window == nil {
+		return APIError{Status: http.StatusBadRequest, Msg: checkin.ErrInvalidToken.Error()}
+	}
+
+	if err := checkin.Verify(Window, checkInRequest.Token, time.Now()); err != nil {
+		if errors.Is(err, checkin.ErrInvalidToken) {
+			return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+		}
+		return APIError{Status: http.StatusGone, Msg: err.Error()}
+	}
+
+	result, err := h.markPresent(Window, claims.ID, types.AttendanceTypeQR, checkInRequest.position())
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, result)
+}
+
+// GenerateCheckInCode sets a new 6-digit code on an open window for rooms
+// where the QR code cannot be shown. It replaces any previous code.
+func (h *Handler) GenerateCheckInCode(w http.ResponseWriter, r *http.Request) error {
+
+	Window, err := h.findOpenCheckInWindow(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	codeRequest := &CheckInCodeRequest{}
+	if r.ContentLength != 0 {
+		if err := decodeRequest(w, r, codeRequest); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	ttl := defaultCodeTTL
+	if codeRequest.TTLSeconds != 0 {
+		ttl = time.Duration(codeRequest.TTLSeconds) * time.Second
+	}
+
+	// A code never outlives its window.
+	expiresAt := now.Add(ttl)
+	if expiresAt.After(Window.ClosesAt) {
+		expiresAt = Window.ClosesAt
+	}
+
+	code, err := checkin.NewCode()
+	if err != nil {
+		return APIError{
+			Status: http.StatusInternalServerError,
+			Msg:    "Error generating check-in code",
+		}
+	}
+
+	if err := h.checkInWindows.SetCheckInCode(Window.ID.Hex(), checkin.HashCode(Window, code), expiresAt); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"windowId":  Window.ID,
+		"code":      code,
+		"expiresAt": expiresAt,
+	})
+}
+
+// CheckInWithCode records the calling student as present using the numeric
+// code of the course's open window. Too many wrong codes lock the student
+// out of that window for a while.
+func (h *Handler) CheckInWithCode(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	checkInRequest := &CheckInWithCodeRequest{}
+	if err := decodeRequest(w, r, checkInRequest); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	Windows, err := h.checkInWindows.GetOpenCheckInWindowsByCourseID(CourseId, now)
+	if err != nil {
+		return err
+	}
+
+	// When several windows are open, the newest one with a code is the one
+	// being shown in the room.
+	var Window *types.CheckInWindow
+	for _, candidate := range Windows {
+		if candidate.CodeHash != "" && (Window == nil || candidate.OpensAt.After(Window.OpensAt)) {
+			Window = candidate
+		}
+	}
+	if Window == nil {
+		return APIError{Status: http.StatusGone, Msg: "There is no active check-in code for this course"}
+	}
+
+	Attempt, err := h.checkInAttempts.FindCheckInAttempt(Window.ID, claims.ID)
+	if err != nil {
+		return err
+	}
+	if Attempt != nil && Attempt.LockedUntil != nil && now.Before(*Attempt.LockedUntil) {
+		return APIError{
+			Status: http.StatusTooManyRequests,
+			Msg:    "Too many wrong codes, try again after " + Attempt.LockedUntil.UTC().Format(time.RFC3339),
+		}
+	}
+
+	switch err := checkin.VerifyCode(Window, checkInRequest.Code, now); {
+	case errors.Is(err, checkin.ErrInvalidCode):
+		return h.recordCodeFailure(Window, claims.ID, now)
+	case err != nil:
+		return APIError{Status: http.StatusGone, Msg: err.Error()}
+	}
+
+	result, err := h.markPresent(Window, claims.ID, types.AttendanceTypeCode, checkInRequest.position())
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) recordCodeFailure(Window *types.CheckInWindow, StudentID primitive.ObjectID, now time.Time) error {
+	Attempt, err := h.checkInAttempts.IncrementCheckInFailures(Window.ID, StudentID)
+	if err != nil {
+		return err
+	}
+
+	if Attempt.Failures >= maxCodeFailures {
+		until := now.Add(codeLockout)
+		if err := h.checkInAttempts.LockCheckInAttempts(Window.ID, StudentID, until); err != nil {
+			return err
+		}
+		return APIError{
+			Status: http.StatusTooManyRequests,
+			Msg:    "Too many wrong codes, try again after " + until.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return APIError{
+		Status: http.StatusBadRequest,
+		Msg:    fmt.Sprintf("%s, %d attempts left", checkin.ErrInvalidCode, maxCodeFailures-Attempt.Failures),
+	}
+}
+
+// markPresent records an enrolled student as present for the window's
+// session, or late when the course policy says so, after checking their
+// position against the course geofence. The session must still be open.
+// Checking in twice is harmless and returns the existing record.
+func (h *Handler) markPresent(Window *types.CheckInWindow, StudentID primitive.ObjectID, method string, pos *checkin.Position) (*types.Attendance, error) {
+
+	Course, err := h.courses.FindCourseByID(Window.CourseID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if Course == nil {
+		return nil, &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	// A window outlives its session only until the teacher closes the
+	// class; after that the absences are final.
+	ClassSession, err := h.classSessions.FindClassSessionByID(Window.SessionID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if ClassSession == nil || ClassSession.Status != types.ClassSessionOpen {
+		return nil, APIError{Status: http.StatusConflict, Msg: "The class session is closed"}
+	}
+
+	enrolled, err := h.isEnrolled(Course.ID, StudentID)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled {
+		return nil, APIError{Status: http.StatusForbidden, Msg: "You are not enrolled in this course"}
+	}
+
+	geo, err := checkin.EvaluateGeoFence(Course.Location, pos)
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case geo.Decision == types.GeoMissing:
+			msg = "Your location is required to check in to this course"
+		case errors.Is(err, checkin.ErrImpreciseFix):
+			msg = fmt.Sprintf("%s, it is accurate to %.0f meters and at most %d are accepted", msg, geo.AccuracyMeters, checkin.MaxAccuracyMeters)
+		default:
+			msg = fmt.Sprintf("%s, you are %.0f meters away", msg, geo.DistanceMeters)
+		}
+		return nil, APIError{Status: http.StatusForbidden, Msg: msg}
+	}
+
+	now := time.Now()
+	checkIn := &types.Attendance{
+		CourseID:  Window.CourseID,
+		StudentID: StudentID,
+		SessionID: Window.SessionID,
+		Type:      method,
+		Date:      now,
+		CheckInAt: &now,
+		GeoCheck:  geo,
+	}
+	checkIn.SetDay(schedule.Location(Course.Schedules))
+	checkIn.SetStatus(types.StatusPresent)
+
+	late := int(now.Sub(ClassSession.Start) / time.Minute)
+	if late > Course.AttendancePolicy().LateAfterMinutes {
+		checkIn.SetStatus(types.StatusLate)
+		checkIn.MinutesLate = late
+	}
+
+	Attendance, err := h.attendances.FindSessionAttendance(Window.SessionID.Hex(), StudentID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if Attendance != nil {
+		if !Attendance.Present {
+			if err := h.attendances.RecordCheckIn(Attendance.ID.Hex(), checkIn); err != nil {
+				return nil, err
+			}
+			Attendance.Type = checkIn.Type
+			Attendance.SetStatus(checkIn.Status)
+			Attendance.MinutesLate = checkIn.MinutesLate
+			Attendance.CheckInAt = checkIn.CheckInAt
+			Attendance.GeoCheck = checkIn.GeoCheck
+		}
+		return Attendance, nil
+	}
+
+	Attendance = checkIn
+
+	result, err := h.attendances.InsertAttendance(Attendance)
+	if err != nil {
+		return nil, err
+	}
+	Attendance.ID = result.InsertedID
+
+	return Attendance, nil
+}
+
+// GetFlaggedCheckIns lists a course's check-ins waiting for a location review.
+func (h *Handler) GetFlaggedCheckIns(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	Attendances, err := h.attendances.GetFlaggedAttendancesByCourseID(CourseId)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, Attendances)
+}
+
+// ReviewCheckIn approves or rejects a check-in flagged by the geofence.
+func (h *Handler) ReviewCheckIn(w http.ResponseWriter, r *http.Request) error {
+
+	AttendanceId := r.PathValue("id")
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	reviewRequest := &ReviewCheckInRequest{}
+	if err := decodeRequest(w, r, reviewRequest); err != nil {
+		return err
+	}
+
+	Attendance, err := h.attendances.FindAttendanceByID(AttendanceId)
+	if err != nil {
+		return err
+	}
+	if Attendance == nil {
+		return &repositories.NotFoundError{Resource: "Attendance"}
+	}
+	if Attendance.GeoCheck == nil || !Attendance.GeoCheck.Flagged {
+		return APIError{Status: http.StatusConflict, Msg: "Attendance is not waiting for review"}
+	}
+
+	if err := h.attendances.ReviewGeoCheck(AttendanceId, *reviewRequest.Approved, claims.ID, time.Now()); err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Check-in reviewed sucessfully.")
+}
+
+func (h *Handler) findOpenCheckInWindow(id string) (*types.CheckInWindow, error) {
+	Window, err := h.checkInWindows.FindCheckInWindowByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if Window == nil {
+		return nil, &repositories.NotFoundError{Resource: "Check-in window"}
+	}
+	if !Window.IsOpen(time.Now()) {
+		return nil, APIError{Status: http.StatusGone, Msg: checkin.ErrWindowClosed.Error()}
+	}
+	return Window, nil
+}
+
+type OpenCheckInRequest struct {
+	SessionId       string `json:"sessionId" validate:"objectid"`
+	DurationSeconds int    `json:"durationSeconds"`
+	RotateSeconds   int    `json:"rotateSeconds"`
+}
+
+type CheckInRequest struct {
+	Token string `json:"token" validate:"required"`
+	CheckInLocation
+}
+
+// CheckInLocation is the optional device position sent with a check-in.
+type CheckInLocation struct {
+	Latitude       *float64 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude      *float64 `json:"longitude" validate:"min=-180,max=180"`
+	AccuracyMeters float64  `json:"accuracy" validate:"min=0,max=100000"`
+}
+
+func (l CheckInLocation) position() *checkin.Position {
+	if l.Latitude == nil || l.Longitude == nil {
+		return nil
+	}
+	return &checkin.Position{Latitude: *l.Latitude, Longitude: *l.Longitude, AccuracyMeters: l.AccuracyMeters}
+}
+
+type CheckInCodeRequest struct {
+	TTLSeconds int `json:"ttlSeconds" validate:"min=0"`
+}
+
+type CheckInWithCodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=6"`
+	CheckInLocation
+}
+
+type ReviewCheckInRequest struct {
+	Approved *bool `json:"approved" validate:"required"`
+}
+
+type checkInState struct {
+	*types.CheckInWindow
+	Token     string    `json:"token"`
+	RotatesAt time.Time `json:"rotatesAt"`
+}
+
+func newCheckInState(Window *types.CheckInWindow, at time.Time) checkInState {
+	token, rotatesAt := checkin.Token(Window, at)
+	return checkInState{CheckInWindow: Window, Token: token, RotatesAt: rotatesAt}
+}
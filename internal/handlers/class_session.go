@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (h *Handler) OpenClassSession(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	openRequest := &ClassSessionRequest{}
+	if err := decodeRequest(w, r, openRequest); err != nil {
+		return err
+	}
+
+	Course, err := h.courses.FindCourseByID(CourseId)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	ClassSession := &types.ClassSession{
+		CourseID: Course.ID,
+		Start:    openRequest.Start,
+		End:      openRequest.End,
+		Room:     openRequest.Room,
+		Type:     openRequest.Type,
+		Status:   types.ClassSessionOpen,
+	}
+	if ClassSession.Start.IsZero() {
+		ClassSession.Start = time.Now()
+	}
+	if !ClassSession.End.IsZero() && ClassSession.End.Before(ClassSession.Start) {
+		return APIError{
+			Status: http.StatusBadRequest,
+			Msg:    "Class Session end must be after its start",
+		}
+	}
+
+	result, err := h.classSessions.InsertClassSession(ClassSession)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) GetAllClassSessionsByCourseID(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	ClassSessions, err := h.classSessions.GetClassSessionsByCourseID(CourseId)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, ClassSessions)
+}
+
+func (h *Handler) CloseClassSession(w http.ResponseWriter, r *http.Request) error {
+
+	ClassSessionId := r.PathValue("id")
+
+	ClassSession, err := h.findClassSession(ClassSessionId)
+	if err != nil {
+		return err
+	}
+	if ClassSession.Status == types.ClassSessionClosed {
+		return APIError{
+			Status: http.StatusConflict,
+			Msg:    "Class Session is already closed",
+		}
+	}
+
+	// Closing early cuts the session short, closing late keeps the planned end.
+	now := time.Now()
+	end := now
+	if !ClassSession.End.IsZero() && ClassSession.End.Before(end) {
+		end = ClassSession.End
+	}
+
+	if err := h.classSessions.CloseClassSession(ClassSessionId, end); err != nil {
+		return err
+	}
+
+	// Check-in windows of the session close with it, so a code or QR token
+	// shared afterwards is useless.
+	Windows, err := h.checkInWindows.GetOpenCheckInWindowsByCourseID(ClassSession.CourseID.Hex(), now)
+	if err != nil {
+		return err
+	}
+	for _, Window := range Windows {
+		if Window.SessionID != ClassSession.ID {
+			continue
+		}
+		if err := h.checkInWindows.CloseCheckInWindow(Window.ID.Hex(), now); err != nil {
+			return err
+		}
+	}
+
+	return WriteJSON(w, http.StatusOK, "Class Session closed sucessfully.")
+}
+
+// GetClassSessionRoll lists every student enrolled in the session's course
+// next to the attendance they have for that session, if any.
+func (h *Handler) GetClassSessionRoll(w http.ResponseWriter, r *http.Request) error {
+
+	ClassSessionId := r.PathValue("id")
+
+	ClassSession, err := h.findClassSession(ClassSessionId)
+	if err != nil {
+		return err
+	}
+
+	Students, err := h.courseStudents(ClassSession.CourseID.Hex())
+	if err != nil {
+		return err
+	}
+
+	Attendances, err := h.attendances.GetAttendancesBySessionID(ClassSessionId)
+	if err != nil {
+		return err
+	}
+
+	byStudent := make(map[primitive.ObjectID]*types.Attendance, len(Attendances))
+	for _, a := range Attendances {
+		byStudent[a.StudentID] = a
+	}
+
+	roll := ClassSessionRoll{Session: ClassSession, Roll: []RollEntry{}}
+	for _, s := range Students {
+		roll.Roll = append(roll.Roll, RollEntry{
+			StudentID:  s.ID,
+			Name:       s.Name,
+			Email:      s.Email,
+			Attendance: byStudent[s.ID],
+		})
+		delete(byStudent, s.ID)
+	}
+
+	// Attendance from students no longer enrolled is still part of the roll.
+	for _, a := range Attendances {
+		if _, ok := byStudent[a.StudentID]; ok {
+			roll.Roll = append(roll.Roll, RollEntry{StudentID: a.StudentID, Attendance: a})
+		}
+	}
+
+	return WriteJSON(w, http.StatusOK, roll)
+}
+
+func (h *Handler) findClassSession(id string) (*types.ClassSession, error) {
+	ClassSession, err := h.classSessions.FindClassSessionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if ClassSession == nil {
+		return nil, &repositories.NotFoundError{Resource: "Class Session"}
+	}
+	return ClassSession, nil
+}
+
+type ClassSessionRequest struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Room  string    `json:"room" validate:"max=100"`
+	Type  string    `json:"type" validate:"max=32"`
+}
+
+type RollEntry struct {
+	StudentID  primitive.ObjectID `json:"studentId"`
+	Name       string             `json:"name,omitempty"`
+	Email      string             `json:"email,omitempty"`
+	Attendance *types.Attendance  `json:"attendance"`
+}
+
+type ClassSessionRoll struct {
+	Session *types.ClassSession `json:"session"`
+	Roll    []RollEntry         `json:"roll"`
+}
@@ -1,176 +1,336 @@
 package handlers
 
 import (
-	"encoding/json"
+	"money-minder/internal/auth"
+	"money-minder/internal/checkin"
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
 	"money-minder/internal/types"
+	"money-minder/internal/validate"
 	"net/http"
+	"time"
 )
 
-func CreateCourse(w http.ResponseWriter, r *http.Request) error {
-	Course := &types.Course{}
-	derr := json.NewDecoder(r.Body).Decode(Course)
+// CreateCourse creates a course. Admins can give it to any teacher;
+// teachers only create courses of their own.
+func (h *Handler) CreateCourse(w http.ResponseWriter, r *http.Request) error {
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Could not create Course, verify that the values are formatted correctly",
-		}
+	req := &CourseRequest{}
+	if err := decodeJSON(w, r, req); err != nil {
+		return err
+	}
+
+	v := validate.Struct(req)
+	if err := checkReference(v, "teacher", req.Teacher, h.teacherExists); err != nil {
+		return err
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	if claims.Role == auth.RoleTeacher && req.Teacher != claims.ID.Hex() {
+		return APIError{Status: http.StatusForbidden, Msg: "Teachers can only create courses they teach"}
 	}
 
-	result, err := courseRepository.InsertCourse(Course)
+	TeacherID, err := repositories.ParseID(req.Teacher)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
+		return err
+	}
+	Course := &types.Course{
+		Name:      req.Name,
+		Code:      req.Code,
+		Teacher:   TeacherID,
+		Schedules: req.Schedules,
+		Location:  req.Location,
+		Policy:    req.Policy,
+	}
+
+	if Course.Location != nil {
+		if err := normalizeGeoFence(Course.Location); err != nil {
+			return err
 		}
 	}
+	if err := schedule.Validate(Course.Schedules); err != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+
+	result, err := h.courses.InsertCourse(Course)
+	if err != nil {
+		return err
+	}
 
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func DeleteCourse(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) DeleteCourse(w http.ResponseWriter, r *http.Request) error {
 
 	CourseId := r.PathValue("id")
 
-	result, err := courseRepository.DeleteCourse(CourseId)
+	result, err := h.courses.DeleteCourse(CourseId)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func GetCourseByID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetCourseByID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Course, err := courseRepository.FindCourseByID(id)
+	Course, err := h.courses.FindCourseByID(id)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
 	}
 
-	return WriteJSON(w, http.StatusOK, Course)
+	return WriteJSON(w, http.StatusOK, newCourseResponse(Course))
 }
 
-func GetAllCoursesByStudentID(w http.ResponseWriter, r *http.Request) error {
+// GetAllCourses pages through every course, optionally searching their
+// name and code.
+func (h *Handler) GetAllCourses(w http.ResponseWriter, r *http.Request) error {
+
+	q, err := listQuery(r, "name", repositories.CourseSorts.Names(), nil)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.courses.ListCourses(q)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, mapPage(page, newCourseResponse))
+}
+
+func (h *Handler) GetAllCoursesByStudentID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Courses, err := courseRepository.GetCoursesByStudentID(id)
+	Courses, err := repositories.UserCourses(h.enrollments, h.courses, id, types.EnrollmentRoleStudent)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, Courses)
+	return WriteJSON(w, http.StatusOK, newCourseResponses(Courses))
 }
 
-func GetAllCoursesByTeacherID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetAllCoursesByTeacherID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Courses, err := courseRepository.GetCoursesByTeacherID(id)
+	Courses, err := h.courses.GetCoursesByTeacherID(id)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
+		return err
+	}
+
+	// Courses the teacher owns come first, then those they were added to.
+	Enrolled, err := repositories.UserCourses(h.enrollments, h.courses, id, types.EnrollmentRoleTeacher)
+	if err != nil {
+		return err
+	}
+	for _, c := range Enrolled {
+		if c.Teacher.Hex() != id {
+			Courses = append(Courses, c)
 		}
 	}
 
-	return WriteJSON(w, http.StatusOK, Courses)
+	return WriteJSON(w, http.StatusOK, newCourseResponses(Courses))
 }
 
-func AddCourseStudent(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) AddCourseStudent(w http.ResponseWriter, r *http.Request) error {
 
 	CourseId := r.PathValue("id")
 
 	addStudentRequest := &CourseStudentRequest{}
-	derr := json.NewDecoder(r.Body).Decode(addStudentRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt add Student to Course, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, addStudentRequest); err != nil {
+		return err
 	}
 
-	err := courseRepository.AddStudent(CourseId, addStudentRequest.StudentId, studentRepository)
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.enroll(CourseId, addStudentRequest.StudentId, types.EnrollmentRoleStudent); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "New Student added sucessfully.")
 }
 
-func RemoveCourseStudent(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) RemoveCourseStudent(w http.ResponseWriter, r *http.Request) error {
 
 	CourseId := r.PathValue("id")
 
 	removeStudentRequest := &CourseStudentRequest{}
-	derr := json.NewDecoder(r.Body).Decode(removeStudentRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt remove Student from Course, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, removeStudentRequest); err != nil {
+		return err
 	}
-	err := courseRepository.RemoveStudent(CourseId, removeStudentRequest.StudentId, studentRepository)
-
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.unenroll(CourseId, removeStudentRequest.StudentId, types.EnrollmentRoleStudent); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "Student deleted sucessfully.")
 }
 
-func UpdateCourseTeacher(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) UpdateCourseTeacher(w http.ResponseWriter, r *http.Request) error {
 
 	CourseId := r.PathValue("id")
 
 	addTeacherRequest := &CourseTeacherRequest{}
-	derr := json.NewDecoder(r.Body).Decode(addTeacherRequest)
+	if err := decodeJSON(w, r, addTeacherRequest); err != nil {
+		return err
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt update Teacher to Course, verify that the values are formatted correctly",
-		}
+	v := validate.Struct(addTeacherRequest)
+	if err := checkReference(v, "teacherId", addTeacherRequest.TeacherId, h.teacherExists); err != nil {
+		return err
+	}
+	if err := v.Err(); err != nil {
+		return err
 	}
 
-	err := courseRepository.UpdateTeacher(CourseId, addTeacherRequest.TeacherId)
+	err := h.courses.UpdateTeacher(CourseId, addTeacherRequest.TeacherId)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "New Course Teacher updated sucessfully.")
 }
 
+// UpdateCourseLocation sets the geofence students must be inside to check in.
+func (h *Handler) UpdateCourseLocation(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	location := &types.GeoFence{}
+	if err := decodeRequest(w, r, location); err != nil {
+		return err
+	}
+	if err := normalizeGeoFence(location); err != nil {
+		return err
+	}
+
+	err := h.courses.UpdateLocation(CourseId, location)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Course location updated sucessfully.")
+}
+
+func (h *Handler) RemoveCourseLocation(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	err := h.courses.UpdateLocation(CourseId, nil)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Course location removed sucessfully.")
+}
+
+// UpdateCourseSchedules replaces the weekly schedule of a course. An empty
+// list removes it.
+func (h *Handler) UpdateCourseSchedules(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	var schedules []types.ScheduleSlot
+	if err := decodeRequest(w, r, &schedules); err != nil {
+		return err
+	}
+	if err := schedule.Validate(schedules); err != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+
+	err := h.courses.UpdateSchedules(CourseId, schedules)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Course schedules updated sucessfully.")
+}
+
+// GetCourseOccurrences expands the course schedule into the classes that
+// happen between the from and to dates (YYYY-MM-DD, both inclusive).
+func (h *Handler) GetCourseOccurrences(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	from, ferr := time.Parse(schedule.DateLayout, r.URL.Query().Get("from"))
+	to, terr := time.Parse(schedule.DateLayout, r.URL.Query().Get("to"))
+	if ferr != nil || terr != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: "from and to must be dates formatted as YYYY-MM-DD"}
+	}
+	if to.Before(from) {
+		return APIError{Status: http.StatusBadRequest, Msg: "to must not be before from"}
+	}
+	if to.Sub(from) > schedule.MaxRange {
+		return APIError{Status: http.StatusBadRequest, Msg: "date range cannot be longer than a year"}
+	}
+
+	Course, err := h.courses.FindCourseByID(CourseId)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	return WriteJSON(w, http.StatusOK, schedule.Expand(Course.Schedules, from, to))
+}
+
+// UpdateCoursePolicy changes the attendance policy of a course: when a
+// check-in counts as late, how many lates make an absence and when a
+// student is at risk. Fields left out keep their current value.
+func (h *Handler) UpdateCoursePolicy(w http.ResponseWriter, r *http.Request) error {
+
+	CourseId := r.PathValue("id")
+
+	Course, err := h.courses.FindCourseByID(CourseId)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	policy := Course.AttendancePolicy()
+	if err := decodeRequest(w, r, &policy); err != nil {
+		return err
+	}
+
+	err = h.courses.UpdatePolicy(CourseId, &policy)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, "Course policy updated sucessfully.")
+}
+
+// normalizeGeoFence defaults the mode to reject and validates the fence.
+func normalizeGeoFence(location *types.GeoFence) error {
+	if location.Mode == "" {
+		location.Mode = types.GeoFenceReject
+	}
+	if err := checkin.ValidateGeoFence(location); err != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+	return nil
+}
+
 type CourseStudentRequest struct {
-	StudentId string `json:"StudentId" bson:"student_id"`
+	StudentId string `json:"StudentId" bson:"student_id" validate:"required,objectid"`
 }
 
 type CourseTeacherRequest struct {
-	TeacherId string `json:"teacherId" bson:"teacher_id"`
+	TeacherId string `json:"teacherId" bson:"teacher_id" validate:"required,objectid"`
 }
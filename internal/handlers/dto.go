@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The types below are what the API shows of students, teachers and
+// courses. Responses are built from them rather than from the stored
+// documents, so credentials and other internal fields never leave the
+// server even when new ones are added to the persistence types.
+
+type StudentResponse struct {
+	ID            primitive.ObjectID `json:"id"`
+	Name          string             `json:"name"`
+	Email         string             `json:"email"`
+	StudentNumber string             `json:"studentNumber,omitempty"`
+	Attendances   []types.Attendance `json:"attendances,omitempty"`
+}
+
+type TeacherResponse struct {
+	ID    primitive.ObjectID `json:"id"`
+	Name  string             `json:"name"`
+	Email string             `json:"email"`
+}
+
+// UserResponse is any account as admins see it.
+type UserResponse struct {
+	ID     primitive.ObjectID `json:"id"`
+	Name   string             `json:"name"`
+	Email  string             `json:"email"`
+	Role   string             `json:"role"`
+	Status string             `json:"status"`
+}
+
+// UserPasswordResponse answers a password reset. TemporaryPassword is only
+// set when the server generated the password, and is never stored.
+type UserPasswordResponse struct {
+	UserResponse
+	TemporaryPassword string `json:"temporaryPassword,omitempty"`
+}
+
+type CourseResponse struct {
+	ID              primitive.ObjectID      `json:"id"`
+	Name            string                  `json:"name"`
+	Code            string                  `json:"code"`
+	Teacher         primitive.ObjectID      `json:"teacher"`
+	Schedules       []types.ScheduleSlot    `json:"schedules,omitempty"`
+	LegacySchedules []string                `json:"legacySchedules,omitempty"`
+	Location        *types.GeoFence         `json:"location,omitempty"`
+	Policy          *types.AttendancePolicy `json:"policy,omitempty"`
+}
+
+// StudentRequest creates a student. Password is the plain text one and is
+// only ever stored hashed.
+type StudentRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	Email         string `json:"email" validate:"required,email,max=254"`
+	Password      string `json:"password" validate:"required,min=8,max=72"`
+	StudentNumber string `json:"studentNumber" validate:"max=32"`
+}
+
+// TeacherRequest creates a teacher, like StudentRequest.
+type TeacherRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// CourseRequest creates a course. Schedules and location are checked by
+// their own packages once the request passes its tags.
+type CourseRequest struct {
+	Name      string                  `json:"name" validate:"required,max=100"`
+	Code      string                  `json:"code" validate:"max=32"`
+	Teacher   string                  `json:"teacher" validate:"required,objectid"`
+	Schedules []types.ScheduleSlot    `json:"schedules" validate:"max=50"`
+	Location  *types.GeoFence         `json:"location"`
+	Policy    *types.AttendancePolicy `json:"policy"`
+}
+
+// AttendanceRequest records attendance by hand. The course can be left out
+// when the attendance is for a class session, and so can the date, which
+// then is the start of the session.
+type AttendanceRequest struct {
+	CourseID    string     `json:"courseId" validate:"objectid"`
+	StudentID   string     `json:"studentId" validate:"required,objectid"`
+	SessionID   string     `json:"sessionId" validate:"objectid"`
+	Type        string     `json:"type" validate:"max=32"`
+	Date        time.Time  `json:"date"`
+	Status      string     `json:"status" validate:"oneof=present absent late excused left-early remote"`
+	Present     bool       `json:"present"`
+	MinutesLate int        `json:"minutesLate" validate:"min=0"`
+	CheckInAt   *time.Time `json:"checkInAt"`
+	CheckOutAt  *time.Time `json:"checkOutAt"`
+	Note        string     `json:"note" validate:"max=500"`
+}
+
+// attendance builds the record to store. Status wins over the older
+// present flag when both are sent.
+func (req *AttendanceRequest) attendance() (*types.Attendance, error) {
+	Attendance := &types.Attendance{
+		Type:        req.Type,
+		Date:        req.Date,
+		MinutesLate: req.MinutesLate,
+		CheckInAt:   req.CheckInAt,
+		CheckOutAt:  req.CheckOutAt,
+		Note:        req.Note,
+	}
+
+	var err error
+	if Attendance.StudentID, err = repositories.ParseID(req.StudentID); err != nil {
+		return nil, err
+	}
+	if req.CourseID != "" {
+		if Attendance.CourseID, err = repositories.ParseID(req.CourseID); err != nil {
+			return nil, err
+		}
+	}
+	if req.SessionID != "" {
+		if Attendance.SessionID, err = repositories.ParseID(req.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	status := req.Status
+	if status == "" {
+		status = types.StatusAbsent
+		if req.Present {
+			status = types.StatusPresent
+		}
+	}
+	Attendance.SetStatus(status)
+
+	return Attendance, nil
+}
+
+func newStudentResponse(Student *types.Student) StudentResponse {
+	return StudentResponse{
+		ID:            Student.ID,
+		Name:          Student.Name,
+		Email:         Student.Email,
+		StudentNumber: Student.Number,
+		Attendances:   Student.Attendances,
+	}
+}
+
+func newStudentResponses(Students []*types.Student) []StudentResponse {
+	responses := make([]StudentResponse, 0, len(Students))
+	for _, s := range Students {
+		responses = append(responses, newStudentResponse(s))
+	}
+	return responses
+}
+
+func newTeacherResponse(Teacher *types.Teacher) TeacherResponse {
+	return TeacherResponse{
+		ID:    Teacher.ID,
+		Name:  Teacher.Name,
+		Email: Teacher.Email,
+	}
+}
+
+func newCourseResponse(Course *types.Course) CourseResponse {
+	return CourseResponse{
+		ID:              Course.ID,
+		Name:            Course.Name,
+		Code:            Course.Code,
+		Teacher:         Course.Teacher,
+		Schedules:       Course.Schedules,
+		LegacySchedules: Course.LegacySchedules,
+		Location:        Course.Location,
+		Policy:          Course.Policy,
+	}
+}
+
+func newCourseResponses(Courses []*types.Course) []CourseResponse {
+	responses := make([]CourseResponse, 0, len(Courses))
+	for _, c := range Courses {
+		responses = append(responses, newCourseResponse(c))
+	}
+	return responses
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// responseTypes is every type the handlers pass to WriteJSON;
+// TestResponseTypesListed fails when one is missing.
+var responseTypes = []any{
+	"",
+	StudentResponse{},
+	TeacherResponse{},
+	UserResponse{},
+	UserPasswordResponse{},
+	CourseResponse{},
+	[]CourseResponse{},
+	repositories.Page[StudentResponse]{},
+	repositories.Page[CourseResponse]{},
+	repositories.Page[UserResponse]{},
+	repositories.Page[*types.Attendance]{},
+	repositories.InsertResult{},
+	repositories.DeleteResult{},
+	map[string]interface{}{},
+	types.Attendance{},
+	[]*types.Attendance{},
+	[]types.AttendanceSummary{},
+	tokenResponse{},
+	checkInState{},
+	ClassSessionRoll{},
+	types.ClassSession{},
+	[]*types.ClassSession{},
+	types.Justification{},
+	[]*types.Justification{},
+	JustificationReview{},
+	types.RiskAssessment{},
+	[]types.RiskAssessment{},
+	RosterImportReport{},
+	types.CourseStats{},
+	[]types.Occurrence{},
+}
+
+// TestResponseTypesListed type-checks the package and looks up the type of
+// every value passed to WriteJSON in responseTypes.
+func TestResponseTypesListed(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*ast.File
+	for _, f := range pkgs["handlers"].Files {
+		files = append(files, f)
+	}
+
+	info := &gotypes.Info{Types: map[ast.Expr]gotypes.TypeAndValue{}}
+	conf := gotypes.Config{Importer: exportImporter(t, fset)}
+	if _, err := conf.Check("money-minder/internal/handlers", fset, files, info); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := map[string]bool{}
+	for _, v := range responseTypes {
+		listed[reflectTypeName(reflect.TypeOf(v))] = true
+	}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 3 {
+				return true
+			}
+			if fn, ok := call.Fun.(*ast.Ident); !ok || fn.Name != "WriteJSON" {
+				return true
+			}
+
+			typ := info.Types[call.Args[2]].Type
+			if basic, ok := typ.(*gotypes.Basic); ok {
+				typ = gotypes.Default(basic)
+			}
+			if ptr, ok := typ.(*gotypes.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			name := gotypes.TypeString(typ, (*gotypes.Package).Path)
+			if !listed[name] {
+				t.Errorf("%s: WriteJSON writes %s, which is not in responseTypes", fset.Position(call.Pos()), name)
+			}
+			return true
+		})
+	}
+}
+
+// exportImporter imports packages from the export data go list leaves in
+// the build cache, which is much faster than type-checking their sources.
+func exportImporter(t *testing.T, fset *token.FileSet) gotypes.Importer {
+	t.Helper()
+
+	out, err := exec.Command("go", "list", "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}", ".").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exports := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, file, _ := strings.Cut(line, "=")
+		exports[path] = file
+	}
+
+	return importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		return os.Open(exports[path])
+	})
+}
+
+// reflectTypeName spells typ the way go/types does with full package paths.
+func reflectTypeName(typ reflect.Type) string {
+	if typ.Name() != "" {
+		if typ.PkgPath() == "" {
+			return typ.Name()
+		}
+		return typ.PkgPath() + "." + typ.Name()
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return "*" + reflectTypeName(typ.Elem())
+	case reflect.Slice:
+		return "[]" + reflectTypeName(typ.Elem())
+	case reflect.Map:
+		return "map[" + reflectTypeName(typ.Key()) + "]" + reflectTypeName(typ.Elem())
+	case reflect.Interface:
+		if typ.NumMethod() == 0 {
+			return "interface{}"
+		}
+	}
+	return typ.String()
+}
+
+// secretWords are the names a serialized field must not have.
+var secretWords = []string{"password", "hash", "secret", "key"}
+
+// allowedSecrets are the fields that carry a secret on purpose: passwords
+// the server generated, handed back once and never stored.
+var allowedSecrets = map[string]bool{
+	"UserPasswordResponse.temporaryPassword": true,
+	"RosterImportRow.temporaryPassword":      true,
+}
+
+func TestResponsesHaveNoSecretFields(t *testing.T) {
+	for _, v := range responseTypes {
+		checkSecretFields(t, reflect.TypeOf(v), map[reflect.Type]bool{})
+	}
+}
+
+func checkSecretFields(t *testing.T, typ reflect.Type, seen map[reflect.Type]bool) {
+	t.Helper()
+
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return
+	}
+	if typ == reflect.TypeOf(time.Time{}) || typ == reflect.TypeOf(primitive.ObjectID{}) {
+		return
+	}
+	seen[typ] = true
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			checkSecretFields(t, f.Type, seen)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		if !allowedSecrets[typ.Name()+"."+name] {
+			for _, word := range secretWords {
+				if strings.Contains(strings.ToLower(name), word) || strings.Contains(strings.ToLower(f.Name), word) {
+					t.Errorf("%s.%s is serialized as %q, which looks like a secret", typ, f.Name, name)
+				}
+			}
+		}
+
+		checkSecretFields(t, f.Type, seen)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// enroll adds the user to the course with role after checking that both
+// exist. Enrolling someone twice is not an error.
+func (h *Handler) enroll(CourseID string, UserID string, role string) error {
+	courseID, userID, err := h.enrollmentParties(CourseID, UserID, role)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.enrollments.Enroll(courseID, userID, role, time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// unenroll drops the user from the course.
+func (h *Handler) unenroll(CourseID string, UserID string, role string) error {
+	courseID, userID, err := h.enrollmentParties(CourseID, UserID, role)
+	if err != nil {
+		return err
+	}
+
+	dropped, err := h.enrollments.Unenroll(courseID, userID, role, time.Now())
+	if err != nil {
+		return err
+	}
+	if !dropped {
+		if role == types.EnrollmentRoleTeacher {
+			return APIError{Status: http.StatusNotFound, Msg: "Teacher is not enrolled in this Course"}
+		}
+		return APIError{Status: http.StatusNotFound, Msg: "Student is not enrolled in this Course"}
+	}
+
+	return nil
+}
+
+// enrollmentParties looks up the course and the student or teacher an
+// enrollment refers to.
+func (h *Handler) enrollmentParties(CourseID string, UserID string, role string) (primitive.ObjectID, primitive.ObjectID, error) {
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	if Course == nil {
+		return primitive.NilObjectID, primitive.NilObjectID, &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	if role == types.EnrollmentRoleTeacher {
+		Teacher, err := h.teachers.FindTeacherByID(UserID)
+		if err != nil {
+			return primitive.NilObjectID, primitive.NilObjectID, err
+		}
+		if Teacher == nil {
+			return primitive.NilObjectID, primitive.NilObjectID, &repositories.NotFoundError{Resource: "Teacher"}
+		}
+		return Course.ID, Teacher.ID, nil
+	}
+
+	Student, err := h.students.FindStudentByID(UserID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, err
+	}
+	if Student == nil {
+		return primitive.NilObjectID, primitive.NilObjectID, &repositories.NotFoundError{Resource: "Student"}
+	}
+	return Course.ID, Student.ID, nil
+}
+
+// courseStudents returns the students enrolled in a course.
+func (h *Handler) courseStudents(CourseID string) ([]*types.Student, error) {
+	Students, err := repositories.CourseStudents(h.enrollments, h.students, CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return Students, nil
+}
+
+// isEnrolled reports whether the student takes the course.
+func (h *Handler) isEnrolled(CourseID primitive.ObjectID, StudentID primitive.ObjectID) (bool, error) {
+	enrolled, err := h.enrollments.IsEnrolled(CourseID, StudentID, types.EnrollmentRoleStudent)
+	if err != nil {
+		return false, err
+	}
+
+	return enrolled, nil
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"money-minder/internal/export"
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
+	"money-minder/internal/types"
+	"net/http"
+	"time"
+)
+
+// ExportCourseAttendance downloads the attendance register of a course as
+// CSV or XLSX. from and to are optional YYYY-MM-DD dates, both inclusive,
+// read in the course's timezone.
+func (h *Handler) ExportCourseAttendance(w http.ResponseWriter, r *http.Request) error {
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		return APIError{Status: http.StatusBadRequest, Msg: "format must be csv or xlsx"}
+	}
+
+	register, rows, err := h.openRegister(r)
+	if err != nil {
+		return err
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	write := export.WriteCSV
+	if format == "xlsx" {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		write = export.WriteXLSX
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": registerFilename(register, format),
+	}))
+	w.WriteHeader(http.StatusOK)
+
+	// Rows are read as they are written and the status line is out, so a
+	// failure can only be logged.
+	if err := write(w, register, rows); err != nil {
+		slog.Error("Export error", "err", err, "course", register.Course.ID.Hex())
+	}
+	return nil
+}
+
+// GetCourseAttendanceReport renders the attendance register of a course as
+// a PDF ready to be signed. It takes the same from and to parameters as the
+// export, plus an optional term name printed in the header.
+func (h *Handler) GetCourseAttendanceReport(w http.ResponseWriter, r *http.Request) error {
+
+	register, rows, err := h.openRegister(r)
+	if err != nil {
+		return err
+	}
+
+	info := export.ReportInfo{
+		Term:        r.URL.Query().Get("term"),
+		GeneratedAt: time.Now(),
+	}
+	Teacher, err := h.teachers.FindTeacherByID(register.Course.Teacher.Hex())
+	if err != nil {
+		return err
+	}
+	if Teacher != nil {
+		info.Teacher = Teacher.Name
+	}
+
+	var report bytes.Buffer
+	if err := export.WritePDF(&report, register, rows, info); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": registerFilename(register, "pdf"),
+	}))
+	w.WriteHeader(http.StatusOK)
+	_, err = report.WriteTo(w)
+	return err
+}
+
+// openRegister loads the course in the {id} path value and lays out its
+// register for the from and to query parameters. Only the classes in the
+// range are loaded up front; the attendances are read as the rows are.
+func (h *Handler) openRegister(r *http.Request) (*export.Register, export.RowSource, error) {
+
+	CourseID := r.PathValue("id")
+
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if Course == nil {
+		return nil, nil, &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	loc := schedule.Location(Course.Schedules)
+
+	var q repositories.Query
+	if v := r.URL.Query().Get("from"); v != "" {
+		if q.From, err = time.ParseInLocation(schedule.DateLayout, v, loc); err != nil {
+			return nil, nil, APIError{Status: http.StatusBadRequest, Msg: "from must be a date formatted as YYYY-MM-DD"}
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		day, err := time.ParseInLocation(schedule.DateLayout, v, loc)
+		if err != nil {
+			return nil, nil, APIError{Status: http.StatusBadRequest, Msg: "to must be a date formatted as YYYY-MM-DD"}
+		}
+		// Query bounds are inclusive: to ends with the last instant of the day.
+		q.To = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return nil, nil, APIError{Status: http.StatusBadRequest, Msg: "to must not be before from"}
+	}
+
+	Students, err := h.courseStudents(CourseID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ClassSessions, err := h.classSessions.GetClassSessionsInRange(CourseID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dates, err := h.attendances.GetUnsessionedAttendanceDates(CourseID, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check-ins are dated when they happen, which for a class that starts
+	// near the end of the range can be after it.
+	records := q
+	for _, ClassSession := range ClassSessions {
+		if records.To.IsZero() {
+			break
+		}
+		if ClassSession.End.IsZero() {
+			records.To = time.Time{}
+		} else if ClassSession.End.After(records.To) {
+			records.To = ClassSession.End
+		}
+	}
+
+	register := export.NewRegister(Course, ClassSessions, dates, loc)
+	rows := register.Rows(Students, func(fn func(*types.Attendance) error) error {
+		return h.attendances.EachAttendanceInRange(CourseID, records, fn)
+	})
+	return register, rows, nil
+}
+
+func registerFilename(register *export.Register, ext string) string {
+	name := register.Course.Code
+	if name == "" {
+		name = register.Course.ID.Hex()
+	}
+	return fmt.Sprintf("%s-attendance.%s", name, ext)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"money-minder/internal/auth"
+	"money-minder/internal/repositories"
+	"money-minder/internal/storage"
+	"net/http"
+)
+
+// Handler serves the API routes on top of the repositories it was built
+// with, so several instances backed by different stores can coexist.
+type Handler struct {
+	students        repositories.StudentRepository
+	teachers        repositories.TeacherRepository
+	admins          repositories.AdminRepository
+	courses         repositories.CourseRepository
+	enrollments     repositories.EnrollmentRepository
+	attendances     repositories.AttendanceRepository
+	classSessions   repositories.ClassSessionRepository
+	checkInWindows  repositories.CheckInWindowRepository
+	checkInAttempts repositories.CheckInAttemptRepository
+	sessions        repositories.SessionRepository
+	justifications  repositories.JustificationRepository
+	riskAssessments repositories.RiskAssessmentRepository
+	tokens          *auth.TokenService
+	files           storage.Storage
+}
+
+func New(store *repositories.Store, tokens *auth.TokenService, files storage.Storage) *Handler {
+	return &Handler{
+		students:        store.Students,
+		teachers:        store.Teachers,
+		admins:          store.Admins,
+		courses:         store.Courses,
+		enrollments:     store.Enrollments,
+		attendances:     store.Attendances,
+		classSessions:   store.ClassSessions,
+		checkInWindows:  store.CheckInWindows,
+		checkInAttempts: store.CheckInAttempts,
+		sessions:        store.Sessions,
+		justifications:  store.Justifications,
+		riskAssessments: store.RiskAssessments,
+		tokens:          tokens,
+		files:           files,
+	}
+}
+
+// callerClaims returns the claims of the authenticated caller.
+func callerClaims(r *http.Request) (*auth.Claims, error) {
+	claims, ok := auth.GetClaims(r.Context())
+	if !ok {
+		return nil, APIError{Status: http.StatusUnauthorized, Msg: "Missing credentials"}
+	}
+	return claims, nil
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"money-minder/internal/repositories"
+	"money-minder/internal/storage"
+	"money-minder/internal/types"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxAttachments    = 5
+	maxAttachmentSize = 10 << 20
+)
+
+// attachmentExtensions lists the file types accepted as evidence, keyed by
+// their sniffed content type.
+var attachmentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+}
+
+// CreateJustification lets a student excuse an absence. It takes a
+// multipart form with courseId, reason, from and until (RFC 3339) and up to
+// five PDF, PNG or JPEG files under attachments.
+func (h *Handler) CreateJustification(w http.ResponseWriter, r *http.Request) error {
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachments*maxAttachmentSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return APIError{
+			Status: http.StatusBadRequest,
+			Msg:    "Couldnt create Justification, send a multipart form no larger than 50MB",
+		}
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	from, ferr := time.Parse(time.RFC3339, r.FormValue("from"))
+	until, uerr := time.Parse(time.RFC3339, r.FormValue("until"))
+	if ferr != nil || uerr != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: "from and until must be RFC 3339 timestamps"}
+	}
+	if until.Before(from) {
+		return APIError{Status: http.StatusBadRequest, Msg: "until must not be before from"}
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if reason == "" {
+		return APIError{Status: http.StatusBadRequest, Msg: "reason is required"}
+	}
+
+	Course, err := h.courses.FindCourseByID(r.FormValue("courseId"))
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	enrolled, err := h.isEnrolled(Course.ID, claims.ID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return APIError{Status: http.StatusForbidden, Msg: "You are not enrolled in this course"}
+	}
+
+	files := r.MultipartForm.File["attachments"]
+	if len(files) > maxAttachments {
+		return APIError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("at most %d attachments are allowed", maxAttachments)}
+	}
+
+	var Attachments []types.Attachment
+	for _, fh := range files {
+		Attachment, err := h.saveAttachment(fh)
+		if err != nil {
+			h.deleteAttachments(Attachments)
+			return err
+		}
+		Attachments = append(Attachments, *Attachment)
+	}
+
+	Justification := &types.Justification{
+		StudentID:   claims.ID,
+		CourseID:    Course.ID,
+		Reason:      reason,
+		From:        from,
+		Until:       until,
+		Attachments: Attachments,
+		Status:      types.JustificationPending,
+		CreatedAt:   time.Now(),
+	}
+
+	result, err := h.justifications.InsertJustification(Justification)
+	if err != nil {
+		h.deleteAttachments(Attachments)
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, result)
+}
+
+// saveAttachment stores one uploaded file after checking its size and that
+// its content, not its declared type, is an accepted format.
+func (h *Handler) saveAttachment(fh *multipart.FileHeader) (*types.Attachment, error) {
+	if fh.Size > maxAttachmentSize {
+		return nil, APIError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("%s is larger than 10MB", fh.Filename)}
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening attachment %s: %w", fh.Filename, err)
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, APIError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("%s could not be read", fh.Filename)}
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := attachmentExtensions[contentType]
+	if !ok {
+		return nil, APIError{Status: http.StatusBadRequest, Msg: fmt.Sprintf("%s must be a PDF, PNG or JPEG file", fh.Filename)}
+	}
+
+	id := primitive.NewObjectID().Hex()
+	size, err := h.files.Save(id+ext, io.MultiReader(bytes.NewReader(head), f))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Attachment{
+		ID:          id,
+		Key:         id + ext,
+		Name:        fh.Filename,
+		ContentType: contentType,
+		Size:        size,
+	}, nil
+}
+
+func (h *Handler) deleteAttachments(Attachments []types.Attachment) {
+	for _, a := range Attachments {
+		h.files.Delete(a.Key)
+	}
+}
+
+func (h *Handler) GetJustificationByID(w http.ResponseWriter, r *http.Request) error {
+
+	Justification, err := h.findJustification(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, Justification)
+}
+
+func (h *Handler) GetAllJustificationsByStudentID(w http.ResponseWriter, r *http.Request) error {
+
+	StudentID := r.PathValue("id")
+
+	Justifications, err := h.justifications.GetJustificationsByStudentID(StudentID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, Justifications)
+}
+
+// GetAllJustificationsByCourseID lists a course's justifications, filtered
+// by the status query parameter when present.
+func (h *Handler) GetAllJustificationsByCourseID(w http.ResponseWriter, r *http.Request) error {
+
+	CourseID := r.PathValue("id")
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", types.JustificationPending, types.JustificationApproved, types.JustificationRejected:
+	default:
+		return APIError{Status: http.StatusBadRequest, Msg: "status must be pending, approved or rejected"}
+	}
+
+	Justifications, err := h.justifications.GetJustificationsByCourseID(CourseID, status)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, Justifications)
+}
+
+// GetJustificationAttachment streams one of the files attached to a
+// justification.
+func (h *Handler) GetJustificationAttachment(w http.ResponseWriter, r *http.Request) error {
+
+	Justification, err := h.findJustification(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	var Attachment *types.Attachment
+	for i := range Justification.Attachments {
+		if Justification.Attachments[i].ID == r.PathValue("attachmentId") {
+			Attachment = &Justification.Attachments[i]
+			break
+		}
+	}
+	if Attachment == nil {
+		return &repositories.NotFoundError{Resource: "Attachment"}
+	}
+
+	f, err := h.files.Open(Attachment.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &repositories.NotFoundError{Resource: "Attachment"}
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", Attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": Attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ReviewJustification approves or rejects a pending justification. Approval
+// turns the student's absences in the course between from and until into
+// excused ones. Approving an approved justification again only retries the
+// excusing, which a failure may have left undone.
+func (h *Handler) ReviewJustification(w http.ResponseWriter, r *http.Request) error {
+
+	JustificationId := r.PathValue("id")
+
+	claims, err := callerClaims(r)
+	if err != nil {
+		return err
+	}
+
+	reviewRequest := &ReviewJustificationRequest{}
+	if err := decodeRequest(w, r, reviewRequest); err != nil {
+		return err
+	}
+
+	Justification, err := h.findJustification(JustificationId)
+	if err != nil {
+		return err
+	}
+
+	status := types.JustificationRejected
+	if *reviewRequest.Approved {
+		status = types.JustificationApproved
+	}
+
+	reviewed, err := h.justifications.ReviewJustification(JustificationId, status, claims.ID, reviewRequest.Comment, time.Now())
+	if err != nil {
+		return err
+	}
+	if !reviewed {
+		Justification, err = h.findJustification(JustificationId)
+		if err != nil {
+			return err
+		}
+		if status != types.JustificationApproved || Justification.Status != types.JustificationApproved {
+			return APIError{Status: http.StatusConflict, Msg: "Justification was already reviewed"}
+		}
+	}
+
+	var excused int64
+	if *reviewRequest.Approved {
+		excused, err = h.attendances.ExcuseAbsences(Justification.CourseID, Justification.StudentID, Justification.From, Justification.Until)
+		if err != nil {
+			return err
+		}
+	}
+
+	return WriteJSON(w, http.StatusOK, JustificationReview{Status: status, Excused: excused})
+}
+
+func (h *Handler) findJustification(id string) (*types.Justification, error) {
+	Justification, err := h.justifications.FindJustificationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if Justification == nil {
+		return nil, &repositories.NotFoundError{Resource: "Justification"}
+	}
+	return Justification, nil
+}
+
+type ReviewJustificationRequest struct {
+	Approved *bool  `json:"approved" validate:"required"`
+	Comment  string `json:"comment" validate:"max=1000"`
+}
+
+// JustificationReview reports the outcome of a review and how many absences
+// it excused.
+type JustificationReview struct {
+	Status  string `json:"status"`
+	Excused int64  `json:"excused"`
+}
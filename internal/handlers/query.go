@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listQuery reads the paging parameters every list endpoint takes:
+//
+//	limit   page size, up to repositories.MaxLimit
+//	cursor  nextCursor of the previous page
+//	sort    one of sorts, prefixed with - for descending order
+//	q       text to search for
+//	from/to dates (YYYY-MM-DD or RFC 3339), both inclusive
+//	status  a status validStatus accepts, e.g. an attendance status
+//
+// Lists ignore the filters that do not apply to them, and status entirely
+// when validStatus is nil.
+func listQuery(r *http.Request, defaultSort string, sorts []string, validStatus func(string) bool) (repositories.Query, error) {
+	params := r.URL.Query()
+	v := &repositories.ValidationError{}
+	q := repositories.Query{Sort: defaultSort}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > repositories.MaxLimit {
+			v.Add("limit", "must be a number between 1 and "+strconv.Itoa(repositories.MaxLimit))
+		}
+		q.Limit = n
+	}
+
+	if sort := params.Get("sort"); sort != "" {
+		q.Desc = strings.HasPrefix(sort, "-")
+		q.Sort = strings.TrimPrefix(sort, "-")
+		if !slices.Contains(sorts, q.Sort) {
+			v.Add("sort", "must be one of "+strings.Join(sorts, ", "))
+		}
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		after, err := repositories.DecodeCursor(cursor)
+		if err != nil {
+			v.Add("cursor", "is not a cursor returned by this list")
+		}
+		q.After = after
+	}
+
+	q.Search = strings.TrimSpace(params.Get("q"))
+	if len(q.Search) > 100 {
+		v.Add("q", "must have at most 100 characters")
+	}
+
+	var ok bool
+	if q.From, ok = queryDate(params.Get("from"), false); !ok {
+		v.Add("from", "must be a date formatted as YYYY-MM-DD or RFC 3339")
+	}
+	if q.To, ok = queryDate(params.Get("to"), true); !ok {
+		v.Add("to", "must be a date formatted as YYYY-MM-DD or RFC 3339")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		v.Add("to", "must not be before from")
+	}
+
+	if validStatus != nil {
+		q.Status = params.Get("status")
+		if q.Status != "" && !validStatus(q.Status) {
+			v.Add("status", "is not a valid status")
+		}
+	}
+
+	return q, v.Err()
+}
+
+// queryDate parses a from or to parameter. A bare date used as an upper
+// bound covers the whole day.
+func queryDate(value string, end bool) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(schedule.DateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, true
+}
+
+// mapPage converts the items of a page, keeping its cursor.
+func mapPage[T, U any](page *repositories.Page[T], convert func(T) U) repositories.Page[U] {
+	items := make([]U, 0, len(page.Items))
+	for _, item := range page.Items {
+		items = append(items, convert(item))
+	}
+	return repositories.Page[U]{Items: items, NextCursor: page.NextCursor}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/risk"
+	"money-minder/internal/types"
+	"net/http"
+	"time"
+)
+
+// GetCourseRisk lists the students of a course who are at risk of failing
+// on attendance or getting close to it. With all=true every enrolled
+// student is listed.
+func (h *Handler) GetCourseRisk(w http.ResponseWriter, r *http.Request) error {
+
+	CourseID := r.PathValue("id")
+
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	Students, err := h.courseStudents(CourseID)
+	if err != nil {
+		return err
+	}
+
+	Attendances, err := h.attendances.GetAttendancesByCourseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	previous, err := h.riskAssessments.GetRiskAssessmentsByCourseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+	assessments := []types.RiskAssessment{}
+	for _, assessment := range risk.Evaluate(Course, Students, Attendances, previous, time.Now()) {
+		if all || assessment.Level != types.RiskOK {
+			assessments = append(assessments, assessment)
+		}
+	}
+
+	return WriteJSON(w, http.StatusOK, assessments)
+}
+
+// GetStudentRisk assesses a student's attendance in every course they are
+// enrolled in.
+func (h *Handler) GetStudentRisk(w http.ResponseWriter, r *http.Request) error {
+
+	StudentID := r.PathValue("id")
+
+	Student, err := h.students.FindStudentByID(StudentID)
+	if err != nil {
+		return err
+	}
+	if Student == nil {
+		return &repositories.NotFoundError{Resource: "Student"}
+	}
+
+	Courses, err := repositories.UserCourses(h.enrollments, h.courses, StudentID, types.EnrollmentRoleStudent)
+	if err != nil {
+		return err
+	}
+
+	Attendances, err := h.attendances.GetAttendancesByStudentID(StudentID)
+	if err != nil {
+		return err
+	}
+
+	previous, err := h.riskAssessments.GetRiskAssessmentsByStudentID(StudentID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	assessments := []types.RiskAssessment{}
+	for _, Course := range Courses {
+		var records []*types.Attendance
+		for _, a := range Attendances {
+			if a.CourseID == Course.ID {
+				records = append(records, a)
+			}
+		}
+
+		var last *types.RiskAssessment
+		for _, p := range previous {
+			if p.CourseID == Course.ID {
+				last = p
+				break
+			}
+		}
+
+		assessments = append(assessments, risk.Assess(Course, *Student, records, last, now))
+	}
+
+	return WriteJSON(w, http.StatusOK, assessments)
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"net/http"
+	"net/mail"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	maxRosterSize = 2 << 20
+	// maxRosterRows keeps the accounts an import may create, each with a
+	// bcrypt hash, well within rosterWriteTimeout.
+	maxRosterRows = 1000
+	// rosterWriteTimeout replaces the server's write timeout for imports,
+	// whose report is the only copy of the temporary passwords.
+	rosterWriteTimeout = 2 * time.Minute
+)
+
+// Outcome of each roster row.
+const (
+	RosterCreated  = "created"
+	RosterEnrolled = "enrolled"
+	RosterSkipped  = "skipped"
+	RosterError    = "error"
+)
+
+// rosterColumns maps the accepted header names to the field they fill.
+var rosterColumns = map[string]string{
+	"name":           "name",
+	"nombre":         "name",
+	"email":          "email",
+	"mail":           "email",
+	"student_number": "number",
+	"studentnumber":  "number",
+	"number":         "number",
+	"legajo":         "number",
+}
+
+type RosterImportRow struct {
+	Line              int                 `json:"line"`
+	Name              string              `json:"name"`
+	Email             string              `json:"email"`
+	StudentNumber     string              `json:"studentNumber,omitempty"`
+	Status            string              `json:"status"`
+	StudentID         *primitive.ObjectID `json:"studentId,omitempty"`
+	TemporaryPassword string              `json:"temporaryPassword,omitempty"`
+	Message           string              `json:"message,omitempty"`
+}
+
+type RosterImportReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Created  int               `json:"created"`
+	Enrolled int               `json:"enrolled"`
+	Skipped  int               `json:"skipped"`
+	Errors   int               `json:"errors"`
+	Rows     []RosterImportRow `json:"rows"`
+}
+
+// ImportCourseRoster enrolls the students listed in a CSV file with a
+// header row naming the name, email and (optional) student_number columns.
+// The file is either the request body or the file field of a multipart
+// form. Students are matched by email; unknown ones get an account with a
+// temporary password that is returned once in the report. With
+// dryRun=true nothing is written and the report says what would happen.
+func (h *Handler) ImportCourseRoster(w http.ResponseWriter, r *http.Request) error {
+
+	CourseID := r.PathValue("id")
+
+	dryRun, err := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	if err != nil && r.URL.Query().Get("dryRun") != "" {
+		return APIError{Status: http.StatusBadRequest, Msg: "dryRun must be true or false"}
+	}
+
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRosterSize)
+	file, err := rosterFile(r)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	Rows, err := parseRoster(file)
+	if err != nil {
+		return APIError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+
+	Enrollments, err := h.enrollments.GetEnrollmentsByCourseID(CourseID, types.EnrollmentRoleStudent)
+	if err != nil {
+		return err
+	}
+	enrolled := make(map[primitive.ObjectID]bool, len(Enrollments))
+	for _, e := range Enrollments {
+		enrolled[e.UserID] = true
+	}
+	seen := make(map[string]int)
+
+	// Hashing the new accounts' passwords is what takes time, so it is done
+	// for all of them at once, after every row is classified.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(rosterWriteTimeout))
+
+	report := RosterImportReport{DryRun: dryRun, Rows: Rows}
+	var newRows []*RosterImportRow
+	for i := range report.Rows {
+		row := &report.Rows[i]
+		if row.Status == RosterError {
+			continue
+		}
+
+		if line, ok := seen[row.Email]; ok {
+			row.Status = RosterSkipped
+			row.Message = fmt.Sprintf("duplicate of line %d", line)
+			continue
+		}
+		seen[row.Email] = row.Line
+		if h.importRosterRow(Course.ID, row, enrolled, dryRun) {
+			newRows = append(newRows, row)
+		}
+	}
+
+	if len(newRows) > 0 {
+		passwords, hashes, err := hashTemporaryPasswords(len(newRows))
+		if err != nil {
+			for _, row := range newRows {
+				rosterRowFailed(Course.ID, row, "could not import row", err)
+			}
+		} else {
+			for i, row := range newRows {
+				h.createRosterStudent(Course.ID, row, passwords[i], hashes[i], enrolled)
+			}
+		}
+	}
+
+	for i := range report.Rows {
+		switch report.Rows[i].Status {
+		case RosterCreated:
+			report.Created++
+		case RosterEnrolled:
+			report.Enrolled++
+		case RosterSkipped:
+			report.Skipped++
+		case RosterError:
+			report.Errors++
+		}
+	}
+
+	return WriteJSON(w, http.StatusOK, report)
+}
+
+// importRosterRow enrolls the student of row when they have an account,
+// recording the outcome on row. It reports whether an account has to be
+// created for them, which createRosterStudent does.
+func (h *Handler) importRosterRow(CourseID primitive.ObjectID, row *RosterImportRow, enrolled map[primitive.ObjectID]bool, dryRun bool) bool {
+
+	Student, err := h.students.FindStudentByEmail(row.Email)
+	if err != nil {
+		rosterRowFailed(CourseID, row, "could not import row", err)
+		return false
+	}
+
+	if Student != nil {
+		row.StudentID = &Student.ID
+		if enrolled[Student.ID] {
+			row.Status, row.Message = RosterSkipped, "already enrolled"
+			return false
+		}
+		if !dryRun {
+			if _, err := h.enrollments.Enroll(CourseID, Student.ID, types.EnrollmentRoleStudent, time.Now()); err != nil {
+				rosterRowFailed(CourseID, row, "could not import row", err)
+				return false
+			}
+		}
+		enrolled[Student.ID] = true
+		row.Status = RosterEnrolled
+		return false
+	}
+
+	row.Status = RosterCreated
+	return !dryRun
+}
+
+// createRosterStudent creates the account of row with a temporary password
+// and enrolls it, recording the outcome on row.
+func (h *Handler) createRosterStudent(CourseID primitive.ObjectID, row *RosterImportRow, password string, hash []byte, enrolled map[primitive.ObjectID]bool) {
+
+	result, err := h.students.InsertStudent(&types.Student{
+		Name:     row.Name,
+		Email:    row.Email,
+		Number:   row.StudentNumber,
+		Password: string(hash),
+	})
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		row.Status, row.Message = RosterError, "email already registered"
+		return
+	}
+	if err != nil {
+		rosterRowFailed(CourseID, row, "could not import row", err)
+		return
+	}
+	row.StudentID = &result.InsertedID
+
+	if _, err := h.enrollments.Enroll(CourseID, result.InsertedID, types.EnrollmentRoleStudent, time.Now()); err != nil {
+		rosterRowFailed(CourseID, row, "account created but not enrolled", err)
+		return
+	}
+	enrolled[result.InsertedID] = true
+	row.TemporaryPassword = password
+}
+
+// hashTemporaryPasswords returns n temporary passwords and their bcrypt
+// hashes, computed by one worker per CPU.
+func hashTemporaryPasswords(n int) ([]string, [][]byte, error) {
+	passwords := make([]string, n)
+	hashes := make([][]byte, n)
+	for i := range passwords {
+		password, err := temporaryPassword()
+		if err != nil {
+			return nil, nil, err
+		}
+		passwords[i] = password
+	}
+
+	jobs := make(chan int)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hash, err := bcrypt.GenerateFromPassword([]byte(passwords[i]), bcrypt.DefaultCost)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				hashes[i] = hash
+			}
+		}()
+	}
+	for i := range passwords {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, nil, err
+	}
+	return passwords, hashes, nil
+}
+
+// rosterRowFailed marks row as failed with msg, logging the error behind
+// it instead of handing storage details to the client.
+func rosterRowFailed(CourseID primitive.ObjectID, row *RosterImportRow, msg string, err error) {
+	slog.Error("Roster row import failed", "course", CourseID.Hex(), "line", row.Line, "err", err)
+	row.Status, row.Message = RosterError, msg
+}
+
+// rosterFile returns the uploaded CSV, read from the file field of a
+// multipart form or from the raw body.
+func rosterFile(r *http.Request) (io.ReadCloser, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	if err := r.ParseMultipartForm(maxRosterSize); err != nil {
+		return nil, APIError{Status: http.StatusBadRequest, Msg: "Couldnt import roster, send a CSV file no larger than 2MB"}
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, APIError{Status: http.StatusBadRequest, Msg: "file is required"}
+	}
+	return file, nil
+}
+
+// parseRoster reads the CSV into one report row per record. Rows that
+// cannot be imported are marked as errors; only a malformed file fails as
+// a whole.
+func parseRoster(file io.Reader) ([]RosterImportRow, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, errors.New("the roster must be no larger than 2MB")
+		}
+		return nil, errors.New("the roster must be a CSV file with a header row")
+	}
+
+	columns := make(map[string]int)
+	for i, title := range header {
+		title = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(title, "\ufeff")))
+		if field, ok := rosterColumns[title]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("the roster header must include an email column")
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("the roster header must include a name column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var Rows []RosterImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, errors.New("the roster must be no larger than 2MB")
+			}
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(Rows) == maxRosterRows {
+			return nil, fmt.Errorf("the roster must have at most %d rows", maxRosterRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := RosterImportRow{
+			Line:          line,
+			Name:          field(record, "name"),
+			Email:         types.NormalizeEmail(field(record, "email")),
+			StudentNumber: field(record, "number"),
+		}
+
+		switch {
+		case row.Name == "" && row.Email == "" && row.StudentNumber == "":
+			continue
+		case row.Name == "":
+			row.Status, row.Message = RosterError, "name is required"
+		case row.Email == "":
+			row.Status, row.Message = RosterError, "email is required"
+		case utf8.RuneCountInString(row.Name) > 100:
+			row.Status, row.Message = RosterError, "name must have at most 100 characters"
+		case len(row.Email) > 254:
+			row.Status, row.Message = RosterError, "email must have at most 254 characters"
+		case utf8.RuneCountInString(row.StudentNumber) > 32:
+			row.Status, row.Message = RosterError, "number must have at most 32 characters"
+		default:
+			if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+				row.Status, row.Message = RosterError, "invalid email"
+			}
+		}
+
+		Rows = append(Rows, row)
+	}
+
+	return Rows, nil
+}
+
+// temporaryPassword returns a random password for accounts created by an
+// import or reset by an admin, to be handed to their owner.
+func temporaryPassword() (string, error) {
+	buf := make([]byte, 9)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
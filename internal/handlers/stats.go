@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"net/http"
+	"time"
+)
+
+// GetCourseStats reports, for every student enrolled in a course, how many
+// classes they attended, missed, were late to or had excused and their
+// attendance percentage, plus the course averages.
+func (h *Handler) GetCourseStats(w http.ResponseWriter, r *http.Request) error {
+
+	CourseID := r.PathValue("id")
+
+	Course, err := h.courses.FindCourseByID(CourseID)
+	if err != nil {
+		return err
+	}
+	if Course == nil {
+		return &repositories.NotFoundError{Resource: "Course"}
+	}
+
+	Students, err := h.courseStudents(CourseID)
+	if err != nil {
+		return err
+	}
+
+	held, err := h.classSessions.CountClassSessionsHeld(CourseID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	counts, err := h.attendances.CountAttendancesByStudent(CourseID)
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, courseStats(Course, Students, held, counts))
+}
+
+// courseStats lines up the per-student counts with the course roll, so
+// enrolled students without records show up with zeros.
+func courseStats(Course *types.Course, Students []*types.Student, held int64, counts []types.AttendanceSummary) *types.CourseStats {
+	stats := &types.CourseStats{
+		CourseID:     Course.ID,
+		SessionsHeld: held,
+		Students:     []types.StudentStats{},
+	}
+
+	byStudent := make(map[string]types.AttendanceSummary, len(counts))
+	for _, c := range counts {
+		byStudent[c.StudentID.Hex()] = c
+	}
+
+	policy := Course.AttendancePolicy()
+	var rateSum float64
+	var rated int
+
+	for _, Student := range Students {
+		summary, ok := byStudent[Student.ID.Hex()]
+		if !ok {
+			summary = types.AttendanceSummary{StudentID: Student.ID}
+		}
+		summary.ApplyPolicy(policy)
+
+		line := types.StudentStats{
+			AttendanceSummary: summary,
+			Name:              Student.Name,
+			AttendanceRate:    summary.AttendanceRate(),
+		}
+		stats.Students = append(stats.Students, line)
+
+		stats.Average.Present += float64(summary.Present)
+		stats.Average.Absent += float64(summary.Absent)
+		stats.Average.Late += float64(summary.Late)
+		stats.Average.Excused += float64(summary.Excused)
+		if line.AttendanceRate != nil {
+			rateSum += *line.AttendanceRate
+			rated++
+		}
+	}
+
+	if n := float64(len(stats.Students)); n > 0 {
+		stats.Average.Present /= n
+		stats.Average.Absent /= n
+		stats.Average.Late /= n
+		stats.Average.Excused /= n
+	}
+	if rated > 0 {
+		rate := rateSum / float64(rated)
+		stats.Average.AttendanceRate = &rate
+	}
+
+	return stats
+}
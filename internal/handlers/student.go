@@ -1,184 +1,140 @@
 package handlers
 
 import (
-	"encoding/json"
-	"money-minder/internal/database"
 	"money-minder/internal/repositories"
 	"money-minder/internal/types"
 	"net/http"
-)
 
-var (
-	service           = database.New()
-	studentRepository = &repositories.StudentRepo{
-		MongoCollection: service.GetCollection("students"),
-	}
-	courseRepository = &repositories.CourseRepo{
-		MongoCollection: service.GetCollection("courses"),
-	}
-	attendanceRepository = &repositories.AttendanceRepo{
-		MongoCollection: service.GetCollection("attendances"),
-	}
+	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateStudent(w http.ResponseWriter, r *http.Request) error {
-	usr := &types.Student{}
-	derr := json.NewDecoder(r.Body).Decode(usr)
+func (h *Handler) CreateStudent(w http.ResponseWriter, r *http.Request) error {
+	req := &StudentRequest{}
+	if err := decodeRequest(w, r, req); err != nil {
+		return err
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt create Student, verify that the values are formatted correctly",
-		}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return APIError{Status: http.StatusInternalServerError, Msg: "Error hashing password"}
 	}
 
-	result, err := studentRepository.InsertStudent(usr)
+	result, err := h.students.InsertStudent(&types.Student{
+		Name:     req.Name,
+		Email:    req.Email,
+		Number:   req.StudentNumber,
+		Password: string(hashedPassword),
+	})
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func GetStudentByID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetStudentByID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Student, err := studentRepository.FindStudentByID(id)
+	Student, err := h.students.FindStudentByID(id)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
+	}
+	if Student == nil {
+		return &repositories.NotFoundError{Resource: "Student"}
 	}
 
-	return WriteJSON(w, http.StatusOK, Student)
+	return WriteJSON(w, http.StatusOK, newStudentResponse(Student))
 }
 
-func AddStudentCourse(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) AddStudentCourse(w http.ResponseWriter, r *http.Request) error {
 
 	StudentId := r.PathValue("id")
 
 	addCourseRequest := &StudentCourseRequest{}
-	derr := json.NewDecoder(r.Body).Decode(addCourseRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt add Course to Student, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, addCourseRequest); err != nil {
+		return err
 	}
 
-	err := studentRepository.AddCourse(StudentId, addCourseRequest.CourseId, courseRepository)
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.enroll(addCourseRequest.CourseId, StudentId, types.EnrollmentRoleStudent); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "New Course added sucessfully.")
 }
 
-func RemoveStudentCourse(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) RemoveStudentCourse(w http.ResponseWriter, r *http.Request) error {
 
 	StudentId := r.PathValue("id")
 
 	removeCourseRequest := &StudentCourseRequest{}
-	derr := json.NewDecoder(r.Body).Decode(removeCourseRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt remove Course to from, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, removeCourseRequest); err != nil {
+		return err
 	}
 
-	err := studentRepository.RemoveCourse(StudentId, removeCourseRequest.CourseId, courseRepository)
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.unenroll(removeCourseRequest.CourseId, StudentId, types.EnrollmentRoleStudent); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "Course deleted sucessfully.")
 }
 
-func AddStudentAttendance(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) AddStudentAttendance(w http.ResponseWriter, r *http.Request) error {
 
 	StudentId := r.PathValue("id")
 
 	addAttendanceRequest := &studentAttendanceRequest{}
-	derr := json.NewDecoder(r.Body).Decode(addAttendanceRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt add Attendance to Student, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, addAttendanceRequest); err != nil {
+		return err
 	}
 
-	err := studentRepository.AddAttendance(StudentId, addAttendanceRequest.AttendanceId, attendanceRepository)
+	err := h.students.AddAttendance(StudentId, addAttendanceRequest.AttendanceId, h.attendances)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "New Attendance added sucessfully.")
 }
 
-func RemoveStudentAttendance(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) RemoveStudentAttendance(w http.ResponseWriter, r *http.Request) error {
 
 	StudentId := r.PathValue("id")
 
 	removeAttendanceRequest := &studentAttendanceRequest{}
-	derr := json.NewDecoder(r.Body).Decode(removeAttendanceRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt remove Attendance from Student, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, removeAttendanceRequest); err != nil {
+		return err
 	}
 
-	err := studentRepository.RemoveAttendance(StudentId, removeAttendanceRequest.AttendanceId, attendanceRepository)
+	err := h.students.RemoveAttendance(StudentId, removeAttendanceRequest.AttendanceId, h.attendances)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "Attendance deleted sucessfully.")
 }
 
-func GetAllStudentsByCourseID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetAllStudentsByCourseID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Students, err := studentRepository.GetStudentsByCourseID(id)
+	q, err := listQuery(r, "name", repositories.StudentSorts.Names(), nil)
+	if err != nil {
+		return err
+	}
 
+	page, err := repositories.ListCourseStudents(h.enrollments, h.students, id, q)
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
-	return WriteJSON(w, http.StatusOK, Students)
+	return WriteJSON(w, http.StatusOK, mapPage(page, newStudentResponse))
 }
 
 type StudentCourseRequest struct {
-	CourseId string `json:"CourseId" bson:"course_id"`
+	CourseId string `json:"CourseId" bson:"course_id" validate:"required,objectid"`
 }
 
 type studentAttendanceRequest struct {
-	AttendanceId string `json:"AttendanceId" bson:"attendance_id"`
+	AttendanceId string `json:"AttendanceId" bson:"attendance_id" validate:"required,objectid"`
 }
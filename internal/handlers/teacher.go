@@ -1,110 +1,88 @@
 package handlers
 
 import (
-	"encoding/json"
 	"money-minder/internal/repositories"
 	"money-minder/internal/types"
 	"net/http"
-)
 
-var (
-	teacherRepository = &repositories.TeacherRepo{
-		MongoCollection: service.GetCollection("teachers"),
-	}
+	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateTeacher(w http.ResponseWriter, r *http.Request) error {
-	usr := &types.Teacher{}
-	derr := json.NewDecoder(r.Body).Decode(usr)
+func (h *Handler) CreateTeacher(w http.ResponseWriter, r *http.Request) error {
+	req := &TeacherRequest{}
+	if err := decodeRequest(w, r, req); err != nil {
+		return err
+	}
 
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt create Teacher, verify that the values are formatted correctly",
-		}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return APIError{Status: http.StatusInternalServerError, Msg: "Error hashing password"}
 	}
 
-	result, err := teacherRepository.InsertTeacher(usr)
+	result, err := h.teachers.InsertTeacher(&types.Teacher{
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: string(hashedPassword),
+	})
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, result)
 }
 
-func GetTeacherByID(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetTeacherByID(w http.ResponseWriter, r *http.Request) error {
 
 	id := r.PathValue("id")
 
-	Teacher, err := teacherRepository.FindTeacherByID(id)
+	Teacher, err := h.teachers.FindTeacherByID(id)
 
 	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+		return err
+	}
+	if Teacher == nil {
+		return &repositories.NotFoundError{Resource: "Teacher"}
 	}
 
-	return WriteJSON(w, http.StatusOK, Teacher)
+	return WriteJSON(w, http.StatusOK, newTeacherResponse(Teacher))
 }
 
-func AddTeacherCourse(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) AddTeacherCourse(w http.ResponseWriter, r *http.Request) error {
 
 	TeacherId := r.PathValue("id")
 
 	addCourseRequest := &TeacherCourseRequest{}
-	derr := json.NewDecoder(r.Body).Decode(addCourseRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt add Course to Teacher, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, addCourseRequest); err != nil {
+		return err
 	}
 
-	err := teacherRepository.AddCourse(TeacherId, addCourseRequest.CourseId, courseRepository)
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.enroll(addCourseRequest.CourseId, TeacherId, types.EnrollmentRoleTeacher); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "New Course added sucessfully.")
 }
 
-func RemoveTeacherCourse(w http.ResponseWriter, r *http.Request) error {
+func (h *Handler) RemoveTeacherCourse(w http.ResponseWriter, r *http.Request) error {
 
 	TeacherId := r.PathValue("id")
 
 	removeCourseRequest := &TeacherCourseRequest{}
-	derr := json.NewDecoder(r.Body).Decode(removeCourseRequest)
-
-	if derr != nil {
-		return APIError{
-			Status: http.StatusBadRequest,
-			Msg:    "Couldnt remove Course to from, verify that the values are formatted correctly",
-		}
+	if err := decodeRequest(w, r, removeCourseRequest); err != nil {
+		return err
 	}
 
-	err := teacherRepository.RemoveCourse(TeacherId, removeCourseRequest.CourseId, courseRepository)
-	if err != nil {
-		return APIError{
-			Status: http.StatusInternalServerError,
-			Msg:    err.Error(),
-		}
+	if err := h.unenroll(removeCourseRequest.CourseId, TeacherId, types.EnrollmentRoleTeacher); err != nil {
+		return err
 	}
 
 	return WriteJSON(w, http.StatusOK, "Course deleted sucessfully.")
 }
 
 type TeacherCourseRequest struct {
-	CourseId string `json:"CourseId" bson:"course_id"`
+	CourseId string `json:"CourseId" bson:"course_id" validate:"required,objectid"`
 }
 
 type TeacherAttendanceRequest struct {
-	AttendanceId string `json:"AttendanceId" bson:"attendance_id"`
+	AttendanceId string `json:"AttendanceId" bson:"attendance_id" validate:"required,objectid"`
 }
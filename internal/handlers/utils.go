@@ -2,7 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"money-minder/internal/repositories"
+	"money-minder/internal/validate"
 	"net/http"
+	"reflect"
+	"strings"
 )
 
 type APIError struct {
@@ -19,3 +25,113 @@ func WriteJSON(w http.ResponseWriter, code int, v any) error {
 	w.WriteHeader(code)
 	return json.NewEncoder(w).Encode(v)
 }
+
+// MaxJSONBody is how large a JSON request body may be.
+const MaxJSONBody = 1 << 20
+
+// decodeJSON reads a single JSON value from the request body into dst.
+// Bodies larger than MaxJSONBody, fields dst does not have and values of
+// the wrong type are rejected; the last two as field errors.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxJSONBody))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("trailing data")
+	}
+
+	var (
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+		tooLarge  *http.MaxBytesError
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &tooLarge):
+		return err
+	case errors.Is(err, io.EOF):
+		return APIError{Status: http.StatusBadRequest, Msg: "The request body is empty"}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return &repositories.ValidationError{Fields: []repositories.FieldError{
+			{Field: field, Message: "must be " + jsonKind(typeErr.Type)},
+		}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &repositories.ValidationError{Fields: []repositories.FieldError{
+			{Field: field, Message: "is not allowed"},
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return APIError{Status: http.StatusBadRequest, Msg: "The request body is not valid JSON"}
+	}
+	return APIError{Status: http.StatusBadRequest, Msg: "The request body must hold a single JSON value"}
+}
+
+// decodeRequest decodes the request body into dst and checks it against
+// the rules in its validate tags.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst any) error {
+	if err := decodeJSON(w, r, dst); err != nil {
+		return err
+	}
+	return validate.Struct(dst).Err()
+}
+
+// jsonKind names a Go type the way a client sees it in JSON.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "an object"
+}
+
+// checkReference adds a field error to v when id is a well-formed ID that
+// exists reports as unknown. Empty and malformed IDs are left to the
+// declarative rules.
+func checkReference(v *repositories.ValidationError, field string, id string, exists func(id string) (bool, error)) error {
+	if _, err := repositories.ParseID(id); err != nil {
+		return nil
+	}
+	ok, err := exists(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		v.Add(field, "does not exist")
+	}
+	return nil
+}
+
+func (h *Handler) courseExists(id string) (bool, error) {
+	Course, err := h.courses.FindCourseByID(id)
+	return Course != nil, err
+}
+
+func (h *Handler) studentExists(id string) (bool, error) {
+	Student, err := h.students.FindStudentByID(id)
+	return Student != nil, err
+}
+
+func (h *Handler) teacherExists(id string) (bool, error) {
+	Teacher, err := h.teachers.FindTeacherByID(id)
+	return Teacher != nil, err
+}
+
+func (h *Handler) classSessionExists(id string) (bool, error) {
+	ClassSession, err := h.classSessions.FindClassSessionByID(id)
+	return ClassSession != nil, err
+}
@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"money-minder/internal/repositories"
+	"money-minder/internal/schedule"
+	"money-minder/internal/types"
+	"time"
+)
+
+const absenceLease = "absence-marker"
+
+// occurrenceLookback bounds how far back scheduled classes are considered,
+// so a fresh deployment does not invent sessions for the whole semester.
+const occurrenceLookback = 24 * time.Hour
+
+type AbsenceConfig struct {
+	// Interval is how often the job runs; zero disables it.
+	Interval time.Duration
+	// Grace is how long after a class ends the job waits before recording
+	// absences, which leaves the teacher time to record attendance by hand.
+	// Students cannot check in once the session is closed.
+	Grace time.Duration
+}
+
+// AbsenceConfigFromEnv reads ABSENCE_INTERVAL (default 1m, 0 disables the
+// job) and ABSENCE_GRACE (default 15m).
+func AbsenceConfigFromEnv() (AbsenceConfig, error) {
+	var cfg AbsenceConfig
+	var err error
+
+	if cfg.Interval, err = durationFromEnv("ABSENCE_INTERVAL", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.Grace, err = durationFromEnv("ABSENCE_GRACE", 15*time.Minute); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// AbsenceMarker records an absence for every enrolled student without
+// an attendance once a class session has ended. Only the replica holding
+// the lease runs it, and every step is idempotent should two runs ever
+// overlap.
+type AbsenceMarker struct {
+	store *repositories.Store
+	cfg   AbsenceConfig
+}
+
+func NewAbsenceMarker(store *repositories.Store, cfg AbsenceConfig) *AbsenceMarker {
+	return &AbsenceMarker{store: store, cfg: cfg}
+}
+
+// Start runs the job every Interval until ctx is cancelled.
+func (m *AbsenceMarker) Start(ctx context.Context) {
+	every(ctx, m.store, absenceLease, m.cfg.Interval, m.Run)
+}
+
+// Run performs one pass as of now. A course or session that fails is
+// logged and skipped so it does not hold back the others; the pass then
+// returns every failure joined.
+func (m *AbsenceMarker) Run(now time.Time) error {
+	endedBefore := now.Add(-m.cfg.Grace)
+
+	var errs []error
+	if err := m.sessionsFromSchedules(now, endedBefore); err != nil {
+		errs = append(errs, err)
+	}
+
+	ClassSessions, err := m.store.ClassSessions.GetClassSessionsAwaitingAbsences(endedBefore)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, ClassSession := range ClassSessions {
+		if err := m.markAbsences(ClassSession, now); err != nil {
+			slog.Error("Recording absences failed", "session", ClassSession.ID.Hex(), "course", ClassSession.CourseID.Hex(), "err", err)
+			errs = append(errs, fmt.Errorf("class session %s: %w", ClassSession.ID.Hex(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sessionsFromSchedules creates a closed session for every scheduled class
+// that ended before endedBefore and that no session covers, so absences are
+// recorded even when the teacher never opened one.
+func (m *AbsenceMarker) sessionsFromSchedules(now, endedBefore time.Time) error {
+	Courses, err := m.store.Courses.FindAllCourses()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range Courses {
+		if err := m.scheduledSessions(&Courses[i], now, endedBefore); err != nil {
+			slog.Error("Creating scheduled sessions failed", "course", Courses[i].ID.Hex(), "err", err)
+			errs = append(errs, fmt.Errorf("course %s: %w", Courses[i].ID.Hex(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// scheduledSessions creates the missing sessions of one course.
+func (m *AbsenceMarker) scheduledSessions(Course *types.Course, now, endedBefore time.Time) error {
+	var (
+		existing []*types.ClassSession
+		err      error
+	)
+	for _, occurrence := range schedule.Expand(Course.Schedules, now.Add(-occurrenceLookback), now) {
+		if occurrence.End.After(endedBefore) || occurrence.End.Before(now.Add(-occurrenceLookback)) {
+			continue
+		}
+
+		if existing == nil {
+			existing, err = m.store.ClassSessions.GetClassSessionsByCourseID(Course.ID.Hex())
+			if err != nil {
+				return err
+			}
+		}
+		if covered(existing, occurrence) {
+			continue
+		}
+
+		ClassSession := &types.ClassSession{
+			CourseID: Course.ID,
+			Start:    occurrence.Start,
+			End:      occurrence.End,
+			Room:     occurrence.Room,
+			Type:     types.ClassSessionTypeScheduled,
+			Status:   types.ClassSessionClosed,
+		}
+		result, err := m.store.ClassSessions.InsertClassSession(ClassSession)
+		if err != nil {
+			return err
+		}
+		ClassSession.ID = result.InsertedID
+		existing = append(existing, ClassSession)
+	}
+
+	return nil
+}
+
+// covered reports whether any session overlaps the occurrence.
+func covered(ClassSessions []*types.ClassSession, occurrence types.Occurrence) bool {
+	for _, s := range ClassSessions {
+		if s.Start.Before(occurrence.End) && (s.End.IsZero() || s.End.After(occurrence.Start)) {
+			return true
+		}
+	}
+	return false
+}
+
+// markAbsences records the session's missing students as absent, or as
+// excused when an approved justification covers the class.
+func (m *AbsenceMarker) markAbsences(ClassSession *types.ClassSession, now time.Time) error {
+	Students, err := repositories.CourseStudents(m.store.Enrollments, m.store.Students, ClassSession.CourseID.Hex())
+	if err != nil {
+		return err
+	}
+
+	Justifications, err := m.store.Justifications.GetJustificationsByCourseID(ClassSession.CourseID.Hex(), types.JustificationApproved)
+	if err != nil {
+		return err
+	}
+
+	loc := time.UTC
+	Course, err := m.store.Courses.FindCourseByID(ClassSession.CourseID.Hex())
+	if err != nil {
+		return err
+	}
+	if Course != nil {
+		loc = schedule.Location(Course.Schedules)
+	}
+
+	for _, Student := range Students {
+		Attendance, err := m.store.Attendances.FindSessionAttendance(ClassSession.ID.Hex(), Student.ID.Hex())
+		if err != nil {
+			return err
+		}
+		if Attendance != nil {
+			continue
+		}
+
+		absence := &types.Attendance{
+			CourseID:  ClassSession.CourseID,
+			StudentID: Student.ID,
+			SessionID: ClassSession.ID,
+			Type:      types.AttendanceTypeAuto,
+			Date:      ClassSession.Start,
+		}
+		absence.SetDay(loc)
+		absence.SetStatus(types.StatusAbsent)
+		for _, Justification := range Justifications {
+			if Justification.StudentID == Student.ID && Justification.Covers(absence.Date) {
+				absence.SetStatus(types.StatusExcused)
+				break
+			}
+		}
+
+		if _, err := m.store.Attendances.InsertAttendance(absence); err != nil {
+			return err
+		}
+	}
+
+	return m.store.ClassSessions.MarkAbsencesRecorded(ClassSession.ID.Hex(), now)
+}
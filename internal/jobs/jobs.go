@@ -0,0 +1,67 @@
+// Package jobs holds the background work the API runs next to the HTTP
+// server.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"money-minder/internal/repositories"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// holder identifies this process when taking leases.
+var holder = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%s", host, primitive.NewObjectID().Hex())
+}()
+
+// every calls run each interval until ctx is cancelled, starting right
+// away. Replicas share the work through the named lease, so only one of
+// them runs the job at a time. A zero interval disables the job.
+func every(ctx context.Context, store *repositories.Store, lease string, interval time.Duration, run func(now time.Time) error) {
+	if interval == 0 {
+		slog.Info("Job disabled", "job", lease)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			now := time.Now()
+			ok, err := store.Leases.AcquireLease(lease, holder, now, now.Add(2*interval))
+			if err != nil {
+				slog.Error("Job lease error", "job", lease, "err", err)
+			} else if ok {
+				if err := run(now); err != nil {
+					slog.Error("Job error", "job", lease, "err", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// durationFromEnv reads a non-negative duration from key, or returns def
+// when it is unset.
+func durationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return def, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return d, nil
+}
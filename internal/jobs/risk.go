@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"money-minder/internal/repositories"
+	"money-minder/internal/risk"
+	"money-minder/internal/types"
+	"time"
+)
+
+const riskLease = "risk-evaluator"
+
+// RiskIntervalFromEnv reads RISK_INTERVAL, how often attendance risk is
+// re-evaluated (default 1h, 0 disables the job).
+func RiskIntervalFromEnv() (time.Duration, error) {
+	return durationFromEnv("RISK_INTERVAL", time.Hour)
+}
+
+// RiskEvaluator periodically assesses every enrolled student against their
+// course policy and stores the result, so it is known since when a student
+// has been at risk.
+type RiskEvaluator struct {
+	store    *repositories.Store
+	interval time.Duration
+}
+
+func NewRiskEvaluator(store *repositories.Store, interval time.Duration) *RiskEvaluator {
+	return &RiskEvaluator{store: store, interval: interval}
+}
+
+// Start runs the job every interval until ctx is cancelled.
+func (e *RiskEvaluator) Start(ctx context.Context) {
+	every(ctx, e.store, riskLease, e.interval, e.Run)
+}
+
+// Run evaluates every course as of now. A course that fails is logged and
+// skipped so it does not hold back the others; the pass then returns every
+// failure joined.
+func (e *RiskEvaluator) Run(now time.Time) error {
+	Courses, err := e.store.Courses.FindAllCourses()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range Courses {
+		if err := e.evaluateCourse(&Courses[i], now); err != nil {
+			slog.Error("Evaluating risk failed", "course", Courses[i].ID.Hex(), "err", err)
+			errs = append(errs, fmt.Errorf("course %s: %w", Courses[i].ID.Hex(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *RiskEvaluator) evaluateCourse(Course *types.Course, now time.Time) error {
+	Students, err := repositories.CourseStudents(e.store.Enrollments, e.store.Students, Course.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	Attendances, err := e.store.Attendances.GetAttendancesByCourseID(Course.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	previous, err := e.store.RiskAssessments.GetRiskAssessmentsByCourseID(Course.ID.Hex())
+	if err != nil {
+		return err
+	}
+	last := make(map[string]string, len(previous))
+	for _, p := range previous {
+		last[p.StudentID.Hex()] = p.Level
+	}
+
+	for _, assessment := range risk.Evaluate(Course, Students, Attendances, previous, now) {
+		if assessment.Level == types.RiskAtRisk && last[assessment.StudentID.Hex()] != types.RiskAtRisk {
+			slog.Info("Student at risk", "course", Course.ID.Hex(), "student", assessment.StudentID.Hex(), "reasons", assessment.Reasons)
+		}
+
+		if err := e.store.RiskAssessments.SaveRiskAssessment(&assessment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AdminRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *AdminRepo) InsertAdmin(usr *types.Admin) (*InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
+	result, err := r.MongoCollection.InsertOne(context.Background(), usr)
+	if err != nil {
+		return nil, AsConflict(err, ErrEmailTaken)
+	}
+
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
+}
+
+func (r *AdminRepo) DeleteAdminByID(usrID string) (*DeleteResult, error) {
+	id, err := ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.MongoCollection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
+}
+
+func (r *AdminRepo) FindAdminByID(usrID string) (*types.Admin, error) {
+	id, err := ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	var usr types.Admin
+	err = r.MongoCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&usr)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usr, nil
+}
+
+func (r *AdminRepo) FindAdminByEmail(email string) (*types.Admin, error) {
+	email = types.NormalizeEmail(email)
+	var usr types.Admin
+	err := r.MongoCollection.FindOne(context.Background(), bson.M{"email": email}).Decode(&usr)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usr, nil
+}
+
+func (r *AdminRepo) CountAdmins() (int64, error) {
+	return r.MongoCollection.CountDocuments(context.Background(), bson.M{})
+}
+
+func (r *AdminRepo) ListAdmins(q Query) (*Page[*types.Admin], error) {
+	return findPage(r.MongoCollection, q, AdminSorts, adminID, q.SearchFilter("name", "email"), q.AccountFilter())
+}
+
+func adminID(a *types.Admin) primitive.ObjectID { return a.ID }
+
+func (r *AdminRepo) SetStatus(usrID string, status string) error {
+	return setAccountField(r.MongoCollection, usrID, "status", status)
+}
+
+func (r *AdminRepo) UpdatePassword(usrID string, hash string) error {
+	return setAccountField(r.MongoCollection, usrID, "password", hash)
+}
+
+// setAccountField sets one field of the account usrID in coll, which holds
+// students, teachers or admins.
+func setAccountField(coll *mongo.Collection, usrID string, field string, value string) error {
+	id, err := ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	_, err = coll.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{field: value}})
+	return err
+}
@@ -4,27 +4,29 @@ import (
 	"context"
 	"fmt"
 	"money-minder/internal/types"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AttendanceRepo struct {
 	MongoCollection *mongo.Collection
 }
 
-func (r *AttendanceRepo) InsertAttendance(Attendance *types.Attendance) (interface{}, error) {
+func (r *AttendanceRepo) InsertAttendance(Attendance *types.Attendance) (*InsertResult, error) {
 	result, err := r.MongoCollection.InsertOne((context.Background()), Attendance)
 	if err != nil {
-		return nil, err
+		return nil, AsConflict(err, ErrDuplicateAttendance)
 	}
 
-	return result, nil
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
 }
 
-func (r *AttendanceRepo) DeleteAttendance(AttendanceID string) (*mongo.DeleteResult, error) {
-	id, err := primitive.ObjectIDFromHex(AttendanceID)
+func (r *AttendanceRepo) DeleteAttendance(AttendanceID string) (*DeleteResult, error) {
+	id, err := ParseID(AttendanceID)
 	if err != nil {
 		return nil, err
 	}
@@ -35,17 +37,26 @@ func (r *AttendanceRepo) DeleteAttendance(AttendanceID string) (*mongo.DeleteRes
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
 }
 
-func (r *AttendanceRepo) UpdatePresent(usrID string, isPresent bool) error {
-	id, err := primitive.ObjectIDFromHex(usrID)
+// UpdateStatus saves the status, Present flag, minutes late, check-in and
+// check-out times and note of Attendance.
+func (r *AttendanceRepo) UpdateStatus(AttendanceID string, Attendance *types.Attendance) error {
+	id, err := ParseID(AttendanceID)
 	if err != nil {
 		return err
 	}
 
-	filter := bson.D{{"_id", id}}
-	update := bson.D{{"$set", bson.D{{"present", isPresent}}}}
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status":       Attendance.Status,
+		"present":      Attendance.Present,
+		"minutes_late": Attendance.MinutesLate,
+		"check_in_at":  Attendance.CheckInAt,
+		"check_out_at": Attendance.CheckOutAt,
+		"note":         Attendance.Note,
+	}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
@@ -56,12 +67,12 @@ func (r *AttendanceRepo) UpdatePresent(usrID string, isPresent bool) error {
 }
 
 func (r *AttendanceRepo) FindAttendanceByID(AttendanceID string) (*types.Attendance, error) {
-	id, err := primitive.ObjectIDFromHex(AttendanceID)
+	id, err := ParseID(AttendanceID)
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.D{{"_id", id}}
+	filter := bson.M{"_id": id}
 
 	var Attendance types.Attendance
 
@@ -80,7 +91,7 @@ func (r *AttendanceRepo) FindAttendanceByID(AttendanceID string) (*types.Attenda
 
 func (r *AttendanceRepo) GetAttendancesByCourseID(id string) ([]*types.Attendance, error) {
 
-	CourseID, err := primitive.ObjectIDFromHex(id)
+	CourseID, err := ParseID(id)
 	if err != nil {
 		return nil, fmt.Errorf("invalid CourseID: %w", err)
 	}
@@ -109,9 +120,72 @@ func (r *AttendanceRepo) GetAttendancesByCourseID(id string) ([]*types.Attendanc
 	return Attendances, nil
 }
 
+func (r *AttendanceRepo) EachAttendanceInRange(CourseID string, q Query, fn func(*types.Attendance) error) error {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID}
+	for field, bounds := range q.DateFilter("date") {
+		filter[field] = bounds
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "student_id", Value: 1}, {Key: "date", Value: 1}})
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find Attendances: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var Attendance types.Attendance
+		if err := cursor.Decode(&Attendance); err != nil {
+			return fmt.Errorf("failed to decode Attendance: %w", err)
+		}
+		if err := fn(&Attendance); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AttendanceRepo) GetUnsessionedAttendanceDates(CourseID string, q Query) ([]time.Time, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID, "session_id": bson.M{"$exists": false}}
+	for field, bounds := range q.DateFilter("date") {
+		filter[field] = bounds
+	}
+
+	values, err := r.MongoCollection.Distinct(context.Background(), "date", filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Attendance dates: %w", err)
+	}
+
+	dates := make([]time.Time, 0, len(values))
+	for _, v := range values {
+		if dt, ok := v.(primitive.DateTime); ok {
+			dates = append(dates, dt.Time().UTC())
+		}
+	}
+
+	return dates, nil
+}
+
 func (r *AttendanceRepo) GetAttendancesByStudentID(StudentID string) ([]*types.Attendance, error) {
 
-	ownerID, err := primitive.ObjectIDFromHex(StudentID)
+	ownerID, err := ParseID(StudentID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid StudentID: %w", err)
 	}
@@ -139,3 +213,239 @@ func (r *AttendanceRepo) GetAttendancesByStudentID(StudentID string) ([]*types.A
 
 	return Attendances, nil
 }
+
+func (r *AttendanceRepo) ListAttendancesByCourseID(CourseID string, q Query) (*Page[*types.Attendance], error) {
+	id, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.listAttendances(bson.M{"course_id": id}, q)
+}
+
+func (r *AttendanceRepo) ListAttendancesByStudentID(StudentID string, q Query) (*Page[*types.Attendance], error) {
+	id, err := ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.listAttendances(bson.M{"student_id": id}, q)
+}
+
+func (r *AttendanceRepo) listAttendances(owner bson.M, q Query) (*Page[*types.Attendance], error) {
+	var byStatus bson.M
+	if q.Status != "" {
+		byStatus = bson.M{"status": q.Status}
+	}
+
+	return findPage(r.MongoCollection, q, AttendanceSorts, attendanceID, owner, q.DateFilter("date"), byStatus)
+}
+
+func attendanceID(a *types.Attendance) primitive.ObjectID { return a.ID }
+
+func (r *AttendanceRepo) GetAttendancesBySessionID(SessionID string) ([]*types.Attendance, error) {
+
+	sessionID, err := ParseID(SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SessionID: %w", err)
+	}
+
+	filter := bson.M{"session_id": sessionID}
+	var Attendances []*types.Attendance
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Attendances: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var Attendance types.Attendance
+		if err := cursor.Decode(&Attendance); err != nil {
+			return nil, fmt.Errorf("failed to decode Attendance: %w", err)
+		}
+		Attendances = append(Attendances, &Attendance)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return Attendances, nil
+}
+
+// FindSessionAttendance returns the attendance a student has for a class
+// session, or nil when none was recorded yet.
+func (r *AttendanceRepo) FindSessionAttendance(SessionID string, StudentID string) (*types.Attendance, error) {
+	sessionID, err := ParseID(SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SessionID: %w", err)
+	}
+
+	studentID, err := ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	filter := bson.M{"session_id": sessionID, "student_id": studentID}
+
+	var Attendance types.Attendance
+
+	err = r.MongoCollection.FindOne(context.Background(), filter).Decode(&Attendance)
+	if err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &Attendance, nil
+}
+
+// RecordCheckIn marks an existing attendance as present through a student
+// check-in.
+func (r *AttendanceRepo) RecordCheckIn(AttendanceID string, checkIn *types.Attendance) error {
+	id, err := ParseID(AttendanceID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"type":         checkIn.Type,
+		"status":       checkIn.Status,
+		"present":      checkIn.Present,
+		"minutes_late": checkIn.MinutesLate,
+		"check_in_at":  checkIn.CheckInAt,
+		"geo_check":    checkIn.GeoCheck,
+	}}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReviewGeoCheck settles a flagged check-in. A rejected check-in becomes an
+// absence.
+func (r *AttendanceRepo) ReviewGeoCheck(AttendanceID string, approved bool, reviewer primitive.ObjectID, at time.Time) error {
+	id, err := ParseID(AttendanceID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": id, "geo_check.flagged": true}
+	set := bson.M{
+		"geo_check.flagged":     false,
+		"geo_check.approved":    approved,
+		"geo_check.reviewed_by": reviewer,
+		"geo_check.reviewed_at": at,
+	}
+	if !approved {
+		set["status"] = types.StatusAbsent
+		set["present"] = false
+		set["minutes_late"] = 0
+	}
+	update := bson.M{"$set": set}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AttendanceRepo) GetFlaggedAttendancesByCourseID(CourseID string) ([]*types.Attendance, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID, "geo_check.flagged": true}
+	var Attendances []*types.Attendance
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Attendances: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var Attendance types.Attendance
+		if err := cursor.Decode(&Attendance); err != nil {
+			return nil, fmt.Errorf("failed to decode Attendance: %w", err)
+		}
+		Attendances = append(Attendances, &Attendance)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return Attendances, nil
+}
+
+// ExcuseAbsences turns a student's absences in a course dated between from
+// and until, inclusive, into excused ones and reports how many changed.
+func (r *AttendanceRepo) ExcuseAbsences(CourseID primitive.ObjectID, StudentID primitive.ObjectID, from time.Time, until time.Time) (int64, error) {
+	filter := bson.M{
+		"course_id":  CourseID,
+		"student_id": StudentID,
+		"status":     types.StatusAbsent,
+		"date":       bson.M{"$gte": from, "$lte": until},
+	}
+	update := bson.M{"$set": bson.M{"status": types.StatusExcused, "present": false}}
+
+	result, err := r.MongoCollection.UpdateMany(context.Background(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// CountAttendancesByStudent counts a course's attendance records by status
+// for each student, in the database rather than in the API.
+func (r *AttendanceRepo) CountAttendancesByStudent(CourseID string) ([]types.AttendanceSummary, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	countStatus := func(status string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, 1, 0}}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"course_id": courseID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$student_id",
+			"total":      bson.M{"$sum": 1},
+			"present":    countStatus(types.StatusPresent),
+			"absent":     countStatus(types.StatusAbsent),
+			"late":       countStatus(types.StatusLate),
+			"excused":    countStatus(types.StatusExcused),
+			"left_early": countStatus(types.StatusLeftEarly),
+			"remote":     countStatus(types.StatusRemote),
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.MongoCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate Attendances: %w", err)
+	}
+
+	var summaries []types.AttendanceSummary
+	if err := cursor.All(context.Background(), &summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode Attendance counts: %w", err)
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CheckInWindowRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *CheckInWindowRepo) InsertCheckInWindow(Window *types.CheckInWindow) (*InsertResult, error) {
+	result, err := r.MongoCollection.InsertOne(context.Background(), Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
+}
+
+func (r *CheckInWindowRepo) FindCheckInWindowByID(WindowID string) (*types.CheckInWindow, error) {
+	id, err := ParseID(WindowID)
+	if err != nil {
+		return nil, err
+	}
+
+	var Window types.CheckInWindow
+
+	err = r.MongoCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&Window)
+	if err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &Window, nil
+}
+
+func (r *CheckInWindowRepo) CloseCheckInWindow(WindowID string, closedAt time.Time) error {
+	id, err := ParseID(WindowID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": id, "closed_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"closed_at": closedAt}}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetCheckInCode replaces the numeric code of a window.
+func (r *CheckInWindowRepo) SetCheckInCode(WindowID string, codeHash string, expiresAt time.Time) error {
+	id, err := ParseID(WindowID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"code_hash": codeHash, "code_expires_at": expiresAt}}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetOpenCheckInWindowsByCourseID returns the windows of a course that
+// accept check-ins at t.
+func (r *CheckInWindowRepo) GetOpenCheckInWindowsByCourseID(CourseID string, t time.Time) ([]*types.CheckInWindow, error) {
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{
+		"course_id": courseID,
+		"opens_at":  bson.M{"$lte": t},
+		"closes_at": bson.M{"$gt": t},
+		"closed_at": bson.M{"$exists": false},
+	}
+	var Windows []*types.CheckInWindow
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CheckInWindows: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var Window types.CheckInWindow
+		if err := cursor.Decode(&Window); err != nil {
+			return nil, fmt.Errorf("failed to decode CheckInWindow: %w", err)
+		}
+		Windows = append(Windows, &Window)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return Windows, nil
+}
+
+type CheckInAttemptRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *CheckInAttemptRepo) FindCheckInAttempt(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error) {
+	var Attempt types.CheckInAttempt
+
+	filter := bson.M{"window_id": WindowID, "student_id": StudentID}
+
+	err := r.MongoCollection.FindOne(context.Background(), filter).Decode(&Attempt)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &Attempt, nil
+}
+
+// IncrementCheckInFailures atomically counts one more wrong code and returns
+// the updated attempt.
+func (r *CheckInAttemptRepo) IncrementCheckInFailures(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error) {
+	filter := bson.M{"window_id": WindowID, "student_id": StudentID}
+	update := bson.M{"$inc": bson.M{"failures": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var Attempt types.CheckInAttempt
+
+	err := r.MongoCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&Attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Attempt, nil
+}
+
+// LockCheckInAttempts blocks a student until the given time and starts their
+// failure count over.
+func (r *CheckInAttemptRepo) LockCheckInAttempts(WindowID primitive.ObjectID, StudentID primitive.ObjectID, until time.Time) error {
+	filter := bson.M{"window_id": WindowID, "student_id": StudentID}
+	update := bson.M{"$set": bson.M{"failures": 0, "locked_until": until}}
+
+	_, err := r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	return err
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ClassSessionRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *ClassSessionRepo) InsertClassSession(ClassSession *types.ClassSession) (*InsertResult, error) {
+	result, err := r.MongoCollection.InsertOne(context.Background(), ClassSession)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
+}
+
+func (r *ClassSessionRepo) FindClassSessionByID(ClassSessionID string) (*types.ClassSession, error) {
+	id, err := ParseID(ClassSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ClassSession types.ClassSession
+
+	err = r.MongoCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&ClassSession)
+	if err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &ClassSession, nil
+}
+
+// GetClassSessionsByCourseID returns the sessions of a course ordered by start time.
+func (r *ClassSessionRepo) GetClassSessionsByCourseID(CourseID string) ([]*types.ClassSession, error) {
+	return r.GetClassSessionsInRange(CourseID, Query{})
+}
+
+func (r *ClassSessionRepo) GetClassSessionsInRange(CourseID string, q Query) ([]*types.ClassSession, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID}
+	for field, bounds := range q.DateFilter("start") {
+		filter[field] = bounds
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "start", Value: 1}})
+	var ClassSessions []*types.ClassSession
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ClassSessions: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var ClassSession types.ClassSession
+		if err := cursor.Decode(&ClassSession); err != nil {
+			return nil, fmt.Errorf("failed to decode ClassSession: %w", err)
+		}
+		ClassSessions = append(ClassSessions, &ClassSession)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return ClassSessions, nil
+}
+
+// CloseClassSession marks an open session as closed and records its end time.
+func (r *ClassSessionRepo) CloseClassSession(ClassSessionID string, end time.Time) error {
+	id, err := ParseID(ClassSessionID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": id, "status": types.ClassSessionOpen}
+	update := bson.M{"$set": bson.M{"status": types.ClassSessionClosed, "end": end}}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetClassSessionsAwaitingAbsences returns sessions that ended before
+// endedBefore and have not had their absences recorded yet.
+func (r *ClassSessionRepo) GetClassSessionsAwaitingAbsences(endedBefore time.Time) ([]*types.ClassSession, error) {
+	filter := bson.M{
+		"absences_marked_at": bson.M{"$exists": false},
+		"end":                bson.M{"$gt": time.Time{}, "$lte": endedBefore},
+	}
+
+	var ClassSessions []*types.ClassSession
+	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ClassSessions: %w", err)
+	}
+	if err := cursor.All(context.Background(), &ClassSessions); err != nil {
+		return nil, fmt.Errorf("failed to decode ClassSessions: %w", err)
+	}
+
+	return ClassSessions, nil
+}
+
+func (r *ClassSessionRepo) MarkAbsencesRecorded(ClassSessionID string, at time.Time) error {
+	id, err := ParseID(ClassSessionID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"absences_marked_at": at}}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CountClassSessionsHeld counts the sessions of a course that started before
+// the given time.
+func (r *ClassSessionRepo) CountClassSessionsHeld(CourseID string, before time.Time) (int64, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID, "start": bson.M{"$lte": before}}
+
+	return r.MongoCollection.CountDocuments(context.Background(), filter)
+}
@@ -14,17 +14,17 @@ type CourseRepo struct {
 	MongoCollection *mongo.Collection
 }
 
-func (r *CourseRepo) InsertCourse(Course *types.Course) (interface{}, error) {
+func (r *CourseRepo) InsertCourse(Course *types.Course) (*InsertResult, error) {
 	result, err := r.MongoCollection.InsertOne((context.Background()), Course)
 	if err != nil {
-		return nil, err
+		return nil, AsConflict(err, ErrCourseCodeTaken)
 	}
 
-	return result, nil
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
 }
 
-func (r *CourseRepo) DeleteCourse(CourseID string) (*mongo.DeleteResult, error) {
-	id, err := primitive.ObjectIDFromHex(CourseID)
+func (r *CourseRepo) DeleteCourse(CourseID string) (*DeleteResult, error) {
+	id, err := ParseID(CourseID)
 	if err != nil {
 		return nil, err
 	}
@@ -35,16 +35,16 @@ func (r *CourseRepo) DeleteCourse(CourseID string) (*mongo.DeleteResult, error)
 	if err != nil {
 		return nil, err
 	}
-	return result, nil
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
 }
 
 func (r *CourseRepo) FindCourseByID(CourseID string) (*types.Course, error) {
-	id, err := primitive.ObjectIDFromHex(CourseID)
+	id, err := ParseID(CourseID)
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.D{{"_id", id}}
+	filter := bson.M{"_id": id}
 
 	var Course types.Course
 
@@ -77,63 +77,59 @@ func (r *CourseRepo) FindAllCourses() ([]types.Course, error) {
 	return Courses, nil
 }
 
-func (r *CourseRepo) UpdateName(CourseID string, newName string) error {
-	id, err := primitive.ObjectIDFromHex(CourseID)
-	if err != nil {
-		return err
+func (r *CourseRepo) FindCoursesByIDs(ids []primitive.ObjectID) ([]*types.Course, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
 
-	filter := bson.D{{"_id", id}}
-	update := bson.D{{"$set", bson.D{{"name", newName}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	var Courses []*types.Course
+	cursor, err := r.MongoCollection.Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to find Courses: %w", err)
+	}
+	if err := cursor.All(context.Background(), &Courses); err != nil {
+		return nil, fmt.Errorf("failed to decode Courses: %w", err)
 	}
 
-	return nil
+	return Courses, nil
 }
 
-func (r *CourseRepo) AddStudent(CourseId string, StudentId string, StudentRepo *StudentRepo) error {
-
-	id, err := primitive.ObjectIDFromHex(CourseId)
-	if err != nil {
-		return err
-	}
+func (r *CourseRepo) ListCourses(q Query) (*Page[*types.Course], error) {
+	return findPage(r.MongoCollection, q, CourseSorts, courseID, q.SearchFilter("name", "code"))
+}
 
-	Student, err := StudentRepo.FindStudentByID(StudentId)
+func courseID(c *types.Course) primitive.ObjectID { return c.ID }
 
+func (r *CourseRepo) UpdateName(CourseID string, newName string) error {
+	id, err := ParseID(CourseID)
 	if err != nil {
-		return fmt.Errorf("failed to find Course: %w", err)
-	}
-	if Student == nil {
-		return fmt.Errorf("Course not found")
+		return err
 	}
 
-	filter := bson.D{{"_id", id}}
-	update := bson.D{{"$push", bson.D{{"students", Student}}}}
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"name": newName}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to add Students to Course: %w", err)
+		return err
 	}
 
 	return nil
 }
 
 func (r *CourseRepo) UpdateTeacher(CourseID string, newTeacherID string) error {
-	id, err := primitive.ObjectIDFromHex(CourseID)
+	id, err := ParseID(CourseID)
 	if err != nil {
 		return err
 	}
 
-	teacherId, err := primitive.ObjectIDFromHex(newTeacherID)
+	teacherId, err := ParseID(newTeacherID)
 	if err != nil {
 		return err
 	}
 
-	filter := bson.D{{"_id", id}}
-	update := bson.D{{"$set", bson.D{{"teacher", teacherId}}}}
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"teacher": teacherId}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
@@ -143,35 +139,9 @@ func (r *CourseRepo) UpdateTeacher(CourseID string, newTeacherID string) error {
 	return nil
 }
 
-func (r *CourseRepo) RemoveStudent(CourseID string, StudentID string, StudentRepo *StudentRepo) error {
-
-	CourseObjectId, err := primitive.ObjectIDFromHex(CourseID)
-	if err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
-	}
-
-	Student, err := StudentRepo.FindStudentByID(StudentID)
-	if err != nil {
-		return fmt.Errorf("failed to find Student: %w", err)
-	}
-	if Student == nil {
-		return fmt.Errorf("Student not found")
-	}
-
-	filter := bson.D{{"_id", CourseObjectId}}
-	update := bson.D{{"$pull", bson.D{{"students", bson.D{{"$eq", Student}}}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to delete Student from Course: %w", err)
-	}
-
-	return nil
-}
-
 func (r *CourseRepo) GetCoursesByTeacherID(TeacherID string) ([]*types.Course, error) {
 
-	ownerID, err := primitive.ObjectIDFromHex(TeacherID)
+	ownerID, err := ParseID(TeacherID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid TeacherID: %w", err)
 	}
@@ -200,33 +170,64 @@ func (r *CourseRepo) GetCoursesByTeacherID(TeacherID string) ([]*types.Course, e
 	return Courses, nil
 }
 
-func (r *CourseRepo) GetCoursesByStudentID(StudentID string) ([]*types.Course, error) {
+// UpdateLocation sets the check-in geofence of a course, or removes it when
+// location is nil.
+func (r *CourseRepo) UpdateLocation(CourseID string, location *types.GeoFence) error {
+	id, err := ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"location": location}}
+	if location == nil {
+		update = bson.M{"$unset": bson.M{"location": ""}}
+	}
 
-	ownerID, err := primitive.ObjectIDFromHex(StudentID)
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
 	if err != nil {
-		return nil, fmt.Errorf("invalid StudentID: %w", err)
+		return err
 	}
 
-	filter := bson.M{"students._id": ownerID}
-	var Courses []*types.Course
+	return nil
+}
 
-	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+// UpdateSchedules replaces the recurring schedule of a course.
+func (r *CourseRepo) UpdateSchedules(CourseID string, schedules []types.ScheduleSlot) error {
+	id, err := ParseID(CourseID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find Courses: %w", err)
+		return err
 	}
-	defer cursor.Close(context.Background())
 
-	for cursor.Next(context.Background()) {
-		var Course types.Course
-		if err := cursor.Decode(&Course); err != nil {
-			return nil, fmt.Errorf("failed to decode Course: %w", err)
-		}
-		Courses = append(Courses, &Course)
+	update := bson.M{"$set": bson.M{"schedules": schedules}}
+	if len(schedules) == 0 {
+		update = bson.M{"$unset": bson.M{"schedules": ""}}
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
 	}
 
-	return Courses, nil
+	return nil
+}
+
+// UpdatePolicy sets the attendance policy of a course, or goes back to the
+// default one when policy is nil.
+func (r *CourseRepo) UpdatePolicy(CourseID string, policy *types.AttendancePolicy) error {
+	id, err := ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"policy": policy}}
+	if policy == nil {
+		update = bson.M{"$unset": bson.M{"policy": ""}}
+	}
+
+	_, err = r.MongoCollection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
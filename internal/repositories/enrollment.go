@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EnrollmentRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+// Enroll adds an active enrollment, reactivating a dropped one. It reports
+// false when the user was already enrolled.
+func (r *EnrollmentRepo) Enroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error) {
+	filter := bson.M{"course_id": CourseID, "user_id": UserID, "role": role}
+
+	reactivate := bson.M{"course_id": CourseID, "user_id": UserID, "role": role, "status": types.EnrollmentDropped}
+	update := bson.M{
+		"$set":   bson.M{"status": types.EnrollmentActive, "enrolled_at": at},
+		"$unset": bson.M{"dropped_at": ""},
+	}
+	result, err := r.MongoCollection.UpdateOne(context.Background(), reactivate, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to enroll: %w", err)
+	}
+	if result.ModifiedCount > 0 {
+		return true, nil
+	}
+
+	insert := bson.M{"$setOnInsert": bson.M{"status": types.EnrollmentActive, "enrolled_at": at}}
+	result, err = r.MongoCollection.UpdateOne(context.Background(), filter, insert, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Someone else enrolled the user at the same time.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to enroll: %w", err)
+	}
+
+	return result.UpsertedCount > 0, nil
+}
+
+// Unenroll marks an active enrollment as dropped. It reports false when
+// the user was not enrolled.
+func (r *EnrollmentRepo) Unenroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error) {
+	filter := bson.M{"course_id": CourseID, "user_id": UserID, "role": role, "status": types.EnrollmentActive}
+	update := bson.M{"$set": bson.M{"status": types.EnrollmentDropped, "dropped_at": at}}
+
+	result, err := r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to unenroll: %w", err)
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *EnrollmentRepo) IsEnrolled(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string) (bool, error) {
+	filter := bson.M{"course_id": CourseID, "user_id": UserID, "role": role, "status": types.EnrollmentActive}
+
+	count, err := r.MongoCollection.CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *EnrollmentRepo) GetEnrollmentsByCourseID(CourseID string, role string) ([]*types.Enrollment, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.findActive(bson.M{"course_id": courseID}, role)
+}
+
+func (r *EnrollmentRepo) GetEnrollmentsByUserID(UserID string, role string) ([]*types.Enrollment, error) {
+
+	userID, err := ParseID(UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UserID: %w", err)
+	}
+
+	return r.findActive(bson.M{"user_id": userID}, role)
+}
+
+// findActive returns the active enrollments matching filter, and role when
+// it is not empty, oldest first.
+func (r *EnrollmentRepo) findActive(filter bson.M, role string) ([]*types.Enrollment, error) {
+	filter["status"] = types.EnrollmentActive
+	if role != "" {
+		filter["role"] = role
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "enrolled_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	var Enrollments []*types.Enrollment
+	cursor, err := r.MongoCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Enrollments: %w", err)
+	}
+	if err := cursor.All(context.Background(), &Enrollments); err != nil {
+		return nil, fmt.Errorf("failed to decode Enrollments: %w", err)
+	}
+
+	return Enrollments, nil
+}
+
+// ListCourseStudents pages through the students enrolled in a course.
+func ListCourseStudents(Enrollments EnrollmentRepository, Students StudentRepository, CourseID string, q Query) (*Page[*types.Student], error) {
+	enrolled, err := Enrollments.GetEnrollmentsByCourseID(CourseID, types.EnrollmentRoleStudent)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(enrolled))
+	for _, e := range enrolled {
+		ids = append(ids, e.UserID)
+	}
+
+	return Students.ListStudents(ids, q)
+}
+
+// CourseStudents returns the students enrolled in a course, in the order
+// they enrolled.
+func CourseStudents(Enrollments EnrollmentRepository, Students StudentRepository, CourseID string) ([]*types.Student, error) {
+	enrolled, err := Enrollments.GetEnrollmentsByCourseID(CourseID, types.EnrollmentRoleStudent)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(enrolled))
+	for _, e := range enrolled {
+		ids = append(ids, e.UserID)
+	}
+
+	found, err := Students.FindStudentsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*types.Student, len(found))
+	for _, s := range found {
+		byID[s.ID] = s
+	}
+
+	result := make([]*types.Student, 0, len(ids))
+	for _, id := range ids {
+		if s, ok := byID[id]; ok {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// UserCourses returns the courses a user is enrolled in with role, in the
+// order they enrolled.
+func UserCourses(Enrollments EnrollmentRepository, Courses CourseRepository, UserID string, role string) ([]*types.Course, error) {
+	enrolled, err := Enrollments.GetEnrollmentsByUserID(UserID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(enrolled))
+	for _, e := range enrolled {
+		ids = append(ids, e.CourseID)
+	}
+
+	found, err := Courses.FindCoursesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*types.Course, len(found))
+	for _, c := range found {
+		byID[c.ID] = c
+	}
+
+	result := make([]*types.Course, 0, len(ids))
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
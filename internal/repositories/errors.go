@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors the repositories and the handlers built on them return for
+// conditions callers can act on. The server maps each one to an HTTP status
+// and error code; anything else is an internal error.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrInvalidID = errors.New("invalid id")
+	ErrForbidden = errors.New("forbidden")
+)
+
+// ErrDuplicateKey is what the in-memory backend returns where MongoDB
+// reports a duplicate key error.
+var ErrDuplicateKey = errors.New("duplicate key error")
+
+// NotFoundError names the missing resource. It matches ErrNotFound.
+type NotFoundError struct {
+	Resource string
+}
+
+func (e *NotFoundError) Error() string { return e.Resource + " not found" }
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ConflictError reports a write rejected because it would duplicate a
+// value that must be unique, such as an account email.
+type ConflictError struct {
+	Msg string
+}
+
+func (e *ConflictError) Error() string { return e.Msg }
+
+var (
+	ErrEmailTaken          = &ConflictError{Msg: "An account with this email already exists"}
+	ErrCourseCodeTaken     = &ConflictError{Msg: "A course with this code already exists"}
+	ErrDuplicateAttendance = &ConflictError{Msg: "The student already has attendance recorded for this class"}
+)
+
+// FieldError is one problem with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists everything wrong with a request at once.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 1 {
+		return e.Fields[0].Field + ": " + e.Fields[0].Message
+	}
+	return fmt.Sprintf("%d invalid fields", len(e.Fields))
+}
+
+// Add records a problem with field.
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e when it holds any field error and nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ParseID decodes a hex ObjectID, failing with ErrInvalidID.
+func ParseID(hex string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %q", ErrInvalidID, hex)
+	}
+	return id, nil
+}
+
+// IsDuplicateKey reports whether err is a duplicate key error from either
+// backend.
+func IsDuplicateKey(err error) bool {
+	return mongo.IsDuplicateKeyError(err) || errors.Is(err, ErrDuplicateKey)
+}
+
+// AsConflict returns conflict when err is a duplicate key error, and err
+// otherwise.
+func AsConflict(err error, conflict *ConflictError) error {
+	if IsDuplicateKey(err) {
+		return conflict
+	}
+	return err
+}
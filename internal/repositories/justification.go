@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JustificationRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *JustificationRepo) InsertJustification(Justification *types.Justification) (*InsertResult, error) {
+	result, err := r.MongoCollection.InsertOne(context.Background(), Justification)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
+}
+
+func (r *JustificationRepo) FindJustificationByID(JustificationID string) (*types.Justification, error) {
+	id, err := ParseID(JustificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var Justification types.Justification
+
+	err = r.MongoCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&Justification)
+	if err != nil {
+
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &Justification, nil
+}
+
+func (r *JustificationRepo) GetJustificationsByStudentID(StudentID string) ([]*types.Justification, error) {
+
+	studentID, err := ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.find(bson.M{"student_id": studentID})
+}
+
+// GetJustificationsByCourseID returns a course's justifications, only those
+// in the given status unless status is empty.
+func (r *JustificationRepo) GetJustificationsByCourseID(CourseID string, status string) ([]*types.Justification, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	filter := bson.M{"course_id": courseID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	return r.find(filter)
+}
+
+func (r *JustificationRepo) find(filter bson.M) ([]*types.Justification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	var Justifications []*types.Justification
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Justifications: %w", err)
+	}
+	if err := cursor.All(context.Background(), &Justifications); err != nil {
+		return nil, fmt.Errorf("failed to decode Justifications: %w", err)
+	}
+
+	return Justifications, nil
+}
+
+// ReviewJustification settles a pending justification. It reports false when
+// the justification was already reviewed, so two reviewers cannot both win.
+func (r *JustificationRepo) ReviewJustification(JustificationID string, status string, reviewer primitive.ObjectID, comment string, at time.Time) (bool, error) {
+	id, err := ParseID(JustificationID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": id, "status": types.JustificationPending}
+	update := bson.M{"$set": bson.M{
+		"status":         status,
+		"reviewed_by":    reviewer,
+		"reviewed_at":    at,
+		"review_comment": comment,
+	}}
+
+	result, err := r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
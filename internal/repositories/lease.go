@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LeaseRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+// AcquireLease takes or renews the named lease for holder until the given
+// time. It fails, without error, while another holder's lease is unexpired:
+// the filter then misses and the upsert collides on _id.
+func (r *LeaseRepo) AcquireLease(name string, holder string, now time.Time, until time.Time) (bool, error) {
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires_at": until}}
+
+	_, err := r.MongoCollection.UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
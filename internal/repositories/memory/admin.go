@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AdminRepo struct {
+	admins *collection[types.Admin]
+}
+
+func NewAdminRepo() *AdminRepo {
+	return &AdminRepo{admins: newCollection(func(usr *types.Admin) (string, bool) {
+		return usr.Email, true
+	})}
+}
+
+func setAdminID(usr *types.Admin, id primitive.ObjectID) { usr.ID = id }
+
+func (r *AdminRepo) InsertAdmin(usr *types.Admin) (*repositories.InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
+	id, err := r.admins.insert(usr.ID, usr, setAdminID)
+	if err != nil {
+		return nil, repositories.AsConflict(err, repositories.ErrEmailTaken)
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *AdminRepo) DeleteAdminByID(usrID string) (*repositories.DeleteResult, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := r.admins.deleteWhere(func(docID primitive.ObjectID, _ *types.Admin) bool {
+		return docID == id
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *AdminRepo) FindAdminByID(usrID string) (*types.Admin, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.admins.get(id)
+}
+
+func (r *AdminRepo) FindAdminByEmail(email string) (*types.Admin, error) {
+	email = types.NormalizeEmail(email)
+	found, err := r.admins.find(func(usr *types.Admin) bool {
+		return usr.Email == email
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *AdminRepo) CountAdmins() (int64, error) {
+	found, err := r.admins.find(nil)
+	return int64(len(found)), err
+}
+
+func (r *AdminRepo) ListAdmins(q repositories.Query) (*repositories.Page[*types.Admin], error) {
+	found, err := r.admins.find(func(usr *types.Admin) bool {
+		return q.Matches(usr.Name, usr.Email) && q.HasAccountStatus(usr.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.PageOf(found, q, repositories.AdminSorts, func(usr *types.Admin) primitive.ObjectID { return usr.ID })
+}
+
+func (r *AdminRepo) SetStatus(usrID string, status string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.admins.update(id, func(usr *types.Admin) error {
+		usr.Status = status
+		return nil
+	})
+}
+
+func (r *AdminRepo) UpdatePassword(usrID string, hash string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.admins.update(id, func(usr *types.Admin) error {
+		usr.Password = hash
+		return nil
+	})
+}
@@ -0,0 +1,296 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttendanceRepo struct {
+	attendances *collection[types.Attendance]
+}
+
+func NewAttendanceRepo() *AttendanceRepo {
+	return &AttendanceRepo{attendances: newCollection(
+		func(a *types.Attendance) (string, bool) {
+			return a.CourseID.Hex() + "/" + a.StudentID.Hex() + "/" + a.Day + "/" + a.SessionID.Hex(), true
+		},
+		func(a *types.Attendance) (string, bool) {
+			return a.SessionID.Hex() + "/" + a.StudentID.Hex(), !a.SessionID.IsZero()
+		},
+	)}
+}
+
+func setAttendanceID(a *types.Attendance, id primitive.ObjectID) { a.ID = id }
+
+func (r *AttendanceRepo) InsertAttendance(Attendance *types.Attendance) (*repositories.InsertResult, error) {
+	id, err := r.attendances.insert(Attendance.ID, Attendance, setAttendanceID)
+	if err != nil {
+		return nil, repositories.AsConflict(err, repositories.ErrDuplicateAttendance)
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *AttendanceRepo) DeleteAttendance(AttendanceID string) (*repositories.DeleteResult, error) {
+	id, err := repositories.ParseID(AttendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := r.attendances.deleteWhere(func(docID primitive.ObjectID, _ *types.Attendance) bool {
+		return docID == id
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *AttendanceRepo) UpdateStatus(AttendanceID string, Attendance *types.Attendance) error {
+	id, err := repositories.ParseID(AttendanceID)
+	if err != nil {
+		return err
+	}
+
+	return r.attendances.update(id, func(a *types.Attendance) error {
+		a.Status = Attendance.Status
+		a.Present = Attendance.Present
+		a.MinutesLate = Attendance.MinutesLate
+		a.CheckInAt = copyTime(Attendance.CheckInAt)
+		a.CheckOutAt = copyTime(Attendance.CheckOutAt)
+		a.Note = Attendance.Note
+		return nil
+	})
+}
+
+func (r *AttendanceRepo) FindAttendanceByID(AttendanceID string) (*types.Attendance, error) {
+	id, err := repositories.ParseID(AttendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.attendances.get(id)
+}
+
+func (r *AttendanceRepo) GetAttendancesByCourseID(id string) ([]*types.Attendance, error) {
+
+	CourseID, err := repositories.ParseID(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.attendances.find(func(a *types.Attendance) bool {
+		return a.CourseID == CourseID
+	})
+}
+
+func (r *AttendanceRepo) EachAttendanceInRange(CourseID string, q repositories.Query, fn func(*types.Attendance) error) error {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	found, err := r.attendances.find(func(a *types.Attendance) bool {
+		return a.CourseID == courseID && q.InRange(a.Date)
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].StudentID != found[j].StudentID {
+			return found[i].StudentID.Hex() < found[j].StudentID.Hex()
+		}
+		return found[i].Date.Before(found[j].Date)
+	})
+	for _, a := range found {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AttendanceRepo) GetUnsessionedAttendanceDates(CourseID string, q repositories.Query) ([]time.Time, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	found, err := r.attendances.find(func(a *types.Attendance) bool {
+		return a.CourseID == courseID && a.SessionID.IsZero() && q.InRange(a.Date)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+	for _, a := range found {
+		date := a.Date.UTC()
+		if !seen[date] {
+			seen[date] = true
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+func (r *AttendanceRepo) GetAttendancesByStudentID(StudentID string) ([]*types.Attendance, error) {
+
+	ownerID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.attendances.find(func(a *types.Attendance) bool {
+		return a.StudentID == ownerID
+	})
+}
+
+func (r *AttendanceRepo) ListAttendancesByCourseID(CourseID string, q repositories.Query) (*repositories.Page[*types.Attendance], error) {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.listAttendances(func(a *types.Attendance) bool { return a.CourseID == id }, q)
+}
+
+func (r *AttendanceRepo) ListAttendancesByStudentID(StudentID string, q repositories.Query) (*repositories.Page[*types.Attendance], error) {
+	id, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.listAttendances(func(a *types.Attendance) bool { return a.StudentID == id }, q)
+}
+
+func (r *AttendanceRepo) listAttendances(owned func(*types.Attendance) bool, q repositories.Query) (*repositories.Page[*types.Attendance], error) {
+	found, err := r.attendances.find(func(a *types.Attendance) bool {
+		return owned(a) && q.InRange(a.Date) && (q.Status == "" || a.Status == q.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.PageOf(found, q, repositories.AttendanceSorts, func(a *types.Attendance) primitive.ObjectID { return a.ID })
+}
+
+func (r *AttendanceRepo) GetAttendancesBySessionID(SessionID string) ([]*types.Attendance, error) {
+
+	sessionID, err := repositories.ParseID(SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SessionID: %w", err)
+	}
+
+	return r.attendances.find(func(a *types.Attendance) bool {
+		return a.SessionID == sessionID
+	})
+}
+
+func (r *AttendanceRepo) FindSessionAttendance(SessionID string, StudentID string) (*types.Attendance, error) {
+	sessionID, err := repositories.ParseID(SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SessionID: %w", err)
+	}
+
+	studentID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	found, err := r.attendances.find(func(a *types.Attendance) bool {
+		return a.SessionID == sessionID && a.StudentID == studentID
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *AttendanceRepo) RecordCheckIn(AttendanceID string, checkIn *types.Attendance) error {
+	id, err := repositories.ParseID(AttendanceID)
+	if err != nil {
+		return err
+	}
+
+	return r.attendances.update(id, func(a *types.Attendance) error {
+		a.Type = checkIn.Type
+		a.Status = checkIn.Status
+		a.Present = checkIn.Present
+		a.MinutesLate = checkIn.MinutesLate
+		a.CheckInAt = copyTime(checkIn.CheckInAt)
+		a.GeoCheck = nil
+		if checkIn.GeoCheck != nil {
+			cp := *checkIn.GeoCheck
+			a.GeoCheck = &cp
+		}
+		return nil
+	})
+}
+
+func (r *AttendanceRepo) ReviewGeoCheck(AttendanceID string, approved bool, reviewer primitive.ObjectID, at time.Time) error {
+	id, err := repositories.ParseID(AttendanceID)
+	if err != nil {
+		return err
+	}
+
+	return r.attendances.update(id, func(a *types.Attendance) error {
+		if a.GeoCheck == nil || !a.GeoCheck.Flagged {
+			return nil
+		}
+		if !approved {
+			a.SetStatus(types.StatusAbsent)
+		}
+		a.GeoCheck.Flagged = false
+		a.GeoCheck.Approved = &approved
+		a.GeoCheck.ReviewedBy = &reviewer
+		a.GeoCheck.ReviewedAt = &at
+		return nil
+	})
+}
+
+func (r *AttendanceRepo) GetFlaggedAttendancesByCourseID(CourseID string) ([]*types.Attendance, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.attendances.find(func(a *types.Attendance) bool {
+		return a.CourseID == courseID && a.GeoCheck != nil && a.GeoCheck.Flagged
+	})
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	cp := *t
+	return &cp
+}
+
+func (r *AttendanceRepo) ExcuseAbsences(CourseID primitive.ObjectID, StudentID primitive.ObjectID, from time.Time, until time.Time) (int64, error) {
+	return r.attendances.updateWhere(func(a *types.Attendance) bool {
+		return a.CourseID == CourseID && a.StudentID == StudentID && a.Status == types.StatusAbsent &&
+			!a.Date.Before(from) && !a.Date.After(until)
+	}, func(a *types.Attendance) bool {
+		a.SetStatus(types.StatusExcused)
+		return true
+	}), nil
+}
+
+func (r *AttendanceRepo) CountAttendancesByStudent(CourseID string) ([]types.AttendanceSummary, error) {
+	Attendances, err := r.GetAttendancesByCourseID(CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.SummarizeAttendances(Attendances), nil
+}
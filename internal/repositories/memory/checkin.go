@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CheckInWindowRepo struct {
+	windows *collection[types.CheckInWindow]
+}
+
+func NewCheckInWindowRepo() *CheckInWindowRepo {
+	return &CheckInWindowRepo{windows: newCollection[types.CheckInWindow]()}
+}
+
+func setCheckInWindowID(w *types.CheckInWindow, id primitive.ObjectID) { w.ID = id }
+
+func (r *CheckInWindowRepo) InsertCheckInWindow(Window *types.CheckInWindow) (*repositories.InsertResult, error) {
+	id, err := r.windows.insert(Window.ID, Window, setCheckInWindowID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *CheckInWindowRepo) FindCheckInWindowByID(WindowID string) (*types.CheckInWindow, error) {
+	id, err := repositories.ParseID(WindowID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.windows.get(id)
+}
+
+func (r *CheckInWindowRepo) CloseCheckInWindow(WindowID string, closedAt time.Time) error {
+	id, err := repositories.ParseID(WindowID)
+	if err != nil {
+		return err
+	}
+
+	r.windows.updateWhere(func(w *types.CheckInWindow) bool {
+		return w.ID == id && w.ClosedAt == nil
+	}, func(w *types.CheckInWindow) bool {
+		w.ClosedAt = &closedAt
+		return true
+	})
+	return nil
+}
+
+func (r *CheckInWindowRepo) SetCheckInCode(WindowID string, codeHash string, expiresAt time.Time) error {
+	id, err := repositories.ParseID(WindowID)
+	if err != nil {
+		return err
+	}
+
+	return r.windows.update(id, func(w *types.CheckInWindow) error {
+		w.CodeHash = codeHash
+		w.CodeExpiresAt = &expiresAt
+		return nil
+	})
+}
+
+func (r *CheckInWindowRepo) GetOpenCheckInWindowsByCourseID(CourseID string, t time.Time) ([]*types.CheckInWindow, error) {
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.windows.find(func(w *types.CheckInWindow) bool {
+		return w.CourseID == courseID && w.IsOpen(t)
+	})
+}
+
+type CheckInAttemptRepo struct {
+	// mu makes the find-or-insert in IncrementCheckInFailures atomic.
+	mu       sync.Mutex
+	attempts *collection[types.CheckInAttempt]
+}
+
+func NewCheckInAttemptRepo() *CheckInAttemptRepo {
+	return &CheckInAttemptRepo{attempts: newCollection[types.CheckInAttempt]()}
+}
+
+func setCheckInAttemptID(a *types.CheckInAttempt, id primitive.ObjectID) { a.ID = id }
+
+func (r *CheckInAttemptRepo) FindCheckInAttempt(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error) {
+	found, err := r.attempts.find(func(a *types.CheckInAttempt) bool {
+		return a.WindowID == WindowID && a.StudentID == StudentID
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *CheckInAttemptRepo) IncrementCheckInFailures(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := func(a *types.CheckInAttempt) bool {
+		return a.WindowID == WindowID && a.StudentID == StudentID
+	}
+
+	if r.attempts.updateWhere(match, func(a *types.CheckInAttempt) bool { a.Failures++; return true }) == 0 {
+		attempt := &types.CheckInAttempt{WindowID: WindowID, StudentID: StudentID, Failures: 1}
+		if _, err := r.attempts.insert(primitive.NilObjectID, attempt, setCheckInAttemptID); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.FindCheckInAttempt(WindowID, StudentID)
+}
+
+func (r *CheckInAttemptRepo) LockCheckInAttempts(WindowID primitive.ObjectID, StudentID primitive.ObjectID, until time.Time) error {
+	r.attempts.updateWhere(func(a *types.CheckInAttempt) bool {
+		return a.WindowID == WindowID && a.StudentID == StudentID
+	}, func(a *types.CheckInAttempt) bool {
+		a.Failures = 0
+		a.LockedUntil = &until
+		return true
+	})
+	return nil
+}
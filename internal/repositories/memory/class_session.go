@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ClassSessionRepo struct {
+	classSessions *collection[types.ClassSession]
+}
+
+func NewClassSessionRepo() *ClassSessionRepo {
+	return &ClassSessionRepo{classSessions: newCollection[types.ClassSession]()}
+}
+
+func setClassSessionID(s *types.ClassSession, id primitive.ObjectID) { s.ID = id }
+
+func (r *ClassSessionRepo) InsertClassSession(ClassSession *types.ClassSession) (*repositories.InsertResult, error) {
+	id, err := r.classSessions.insert(ClassSession.ID, ClassSession, setClassSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *ClassSessionRepo) FindClassSessionByID(ClassSessionID string) (*types.ClassSession, error) {
+	id, err := repositories.ParseID(ClassSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.classSessions.get(id)
+}
+
+func (r *ClassSessionRepo) GetClassSessionsByCourseID(CourseID string) ([]*types.ClassSession, error) {
+	return r.GetClassSessionsInRange(CourseID, repositories.Query{})
+}
+
+func (r *ClassSessionRepo) GetClassSessionsInRange(CourseID string, q repositories.Query) ([]*types.ClassSession, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	found, err := r.classSessions.find(func(s *types.ClassSession) bool {
+		return s.CourseID == courseID && q.InRange(s.Start)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Start.Before(found[j].Start) })
+	return found, nil
+}
+
+func (r *ClassSessionRepo) CloseClassSession(ClassSessionID string, end time.Time) error {
+	id, err := repositories.ParseID(ClassSessionID)
+	if err != nil {
+		return err
+	}
+
+	r.classSessions.updateWhere(func(s *types.ClassSession) bool {
+		return s.ID == id && s.Status == types.ClassSessionOpen
+	}, func(s *types.ClassSession) bool {
+		s.Status = types.ClassSessionClosed
+		s.End = end
+		return true
+	})
+	return nil
+}
+
+func (r *ClassSessionRepo) GetClassSessionsAwaitingAbsences(endedBefore time.Time) ([]*types.ClassSession, error) {
+	return r.classSessions.find(func(s *types.ClassSession) bool {
+		return s.AbsencesMarkedAt == nil && !s.End.IsZero() && !s.End.After(endedBefore)
+	})
+}
+
+func (r *ClassSessionRepo) MarkAbsencesRecorded(ClassSessionID string, at time.Time) error {
+	id, err := repositories.ParseID(ClassSessionID)
+	if err != nil {
+		return err
+	}
+
+	return r.classSessions.update(id, func(s *types.ClassSession) error {
+		s.AbsencesMarkedAt = &at
+		return nil
+	})
+}
+
+func (r *ClassSessionRepo) CountClassSessionsHeld(CourseID string, before time.Time) (int64, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	found, err := r.classSessions.find(func(s *types.ClassSession) bool {
+		return s.CourseID == courseID && !s.Start.After(before)
+	})
+	return int64(len(found)), err
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CourseRepo struct {
+	courses *collection[types.Course]
+}
+
+func NewCourseRepo() *CourseRepo {
+	return &CourseRepo{courses: newCollection(func(c *types.Course) (string, bool) {
+		return c.Code, c.Code != ""
+	})}
+}
+
+func setCourseID(c *types.Course, id primitive.ObjectID) { c.ID = id }
+
+func (r *CourseRepo) InsertCourse(Course *types.Course) (*repositories.InsertResult, error) {
+	id, err := r.courses.insert(Course.ID, Course, setCourseID)
+	if err != nil {
+		return nil, repositories.AsConflict(err, repositories.ErrCourseCodeTaken)
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *CourseRepo) DeleteCourse(CourseID string) (*repositories.DeleteResult, error) {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := r.courses.deleteWhere(func(docID primitive.ObjectID, _ *types.Course) bool {
+		return docID == id
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *CourseRepo) FindCourseByID(CourseID string) (*types.Course, error) {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.courses.get(id)
+}
+
+func (r *CourseRepo) FindAllCourses() ([]types.Course, error) {
+	found, err := r.courses.find(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var Courses []types.Course
+	for _, c := range found {
+		Courses = append(Courses, *c)
+	}
+	return Courses, nil
+}
+
+func (r *CourseRepo) FindCoursesByIDs(ids []primitive.ObjectID) ([]*types.Course, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	return r.courses.find(func(c *types.Course) bool {
+		return wanted[c.ID]
+	})
+}
+
+func (r *CourseRepo) ListCourses(q repositories.Query) (*repositories.Page[*types.Course], error) {
+	found, err := r.courses.find(func(c *types.Course) bool {
+		return q.Matches(c.Name, c.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.PageOf(found, q, repositories.CourseSorts, func(c *types.Course) primitive.ObjectID { return c.ID })
+}
+
+func (r *CourseRepo) UpdateName(CourseID string, newName string) error {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	return r.courses.update(id, func(c *types.Course) error {
+		c.Name = newName
+		return nil
+	})
+}
+
+func (r *CourseRepo) UpdateTeacher(CourseID string, newTeacherID string) error {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	teacherId, err := repositories.ParseID(newTeacherID)
+	if err != nil {
+		return err
+	}
+
+	return r.courses.update(id, func(c *types.Course) error {
+		c.Teacher = teacherId
+		return nil
+	})
+}
+
+func (r *CourseRepo) GetCoursesByTeacherID(TeacherID string) ([]*types.Course, error) {
+
+	ownerID, err := repositories.ParseID(TeacherID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TeacherID: %w", err)
+	}
+
+	return r.courses.find(func(c *types.Course) bool {
+		return c.Teacher == ownerID
+	})
+}
+
+func (r *CourseRepo) UpdateLocation(CourseID string, location *types.GeoFence) error {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	return r.courses.update(id, func(c *types.Course) error {
+		c.Location = nil
+		if location != nil {
+			cp := *location
+			c.Location = &cp
+		}
+		return nil
+	})
+}
+
+func (r *CourseRepo) UpdateSchedules(CourseID string, schedules []types.ScheduleSlot) error {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	return r.courses.update(id, func(c *types.Course) error {
+		c.Schedules = nil
+		for _, slot := range schedules {
+			slot.Exceptions = append([]string(nil), slot.Exceptions...)
+			c.Schedules = append(c.Schedules, slot)
+		}
+		return nil
+	})
+}
+
+func (r *CourseRepo) UpdatePolicy(CourseID string, policy *types.AttendancePolicy) error {
+	id, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return err
+	}
+
+	return r.courses.update(id, func(c *types.Course) error {
+		c.Policy = nil
+		if policy != nil {
+			cp := *policy
+			c.Policy = &cp
+		}
+		return nil
+	})
+}
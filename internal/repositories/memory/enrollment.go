@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EnrollmentRepo struct {
+	// mu makes the look-up-then-insert in Enroll atomic.
+	mu          sync.Mutex
+	enrollments *collection[types.Enrollment]
+}
+
+func NewEnrollmentRepo() *EnrollmentRepo {
+	return &EnrollmentRepo{enrollments: newCollection[types.Enrollment]()}
+}
+
+func setEnrollmentID(e *types.Enrollment, id primitive.ObjectID) { e.ID = id }
+
+func (r *EnrollmentRepo) Enroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := false
+	changed := r.enrollments.updateWhere(func(e *types.Enrollment) bool {
+		return e.CourseID == CourseID && e.UserID == UserID && e.Role == role
+	}, func(e *types.Enrollment) bool {
+		found = true
+		if e.Status == types.EnrollmentActive {
+			return false
+		}
+		e.Status = types.EnrollmentActive
+		e.EnrolledAt = at
+		e.DroppedAt = nil
+		return true
+	})
+	if found {
+		return changed > 0, nil
+	}
+
+	_, err := r.enrollments.insert(primitive.NilObjectID, &types.Enrollment{
+		CourseID:   CourseID,
+		UserID:     UserID,
+		Role:       role,
+		Status:     types.EnrollmentActive,
+		EnrolledAt: at,
+	}, setEnrollmentID)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *EnrollmentRepo) Unenroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error) {
+	changed := r.enrollments.updateWhere(func(e *types.Enrollment) bool {
+		return e.CourseID == CourseID && e.UserID == UserID && e.Role == role && e.Status == types.EnrollmentActive
+	}, func(e *types.Enrollment) bool {
+		e.Status = types.EnrollmentDropped
+		e.DroppedAt = &at
+		return true
+	})
+
+	return changed > 0, nil
+}
+
+func (r *EnrollmentRepo) IsEnrolled(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string) (bool, error) {
+	found, err := r.enrollments.find(func(e *types.Enrollment) bool {
+		return e.CourseID == CourseID && e.UserID == UserID && e.Role == role && e.Status == types.EnrollmentActive
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(found) > 0, nil
+}
+
+func (r *EnrollmentRepo) GetEnrollmentsByCourseID(CourseID string, role string) ([]*types.Enrollment, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.findActive(func(e *types.Enrollment) bool {
+		return e.CourseID == courseID
+	}, role)
+}
+
+func (r *EnrollmentRepo) GetEnrollmentsByUserID(UserID string, role string) ([]*types.Enrollment, error) {
+
+	userID, err := repositories.ParseID(UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UserID: %w", err)
+	}
+
+	return r.findActive(func(e *types.Enrollment) bool {
+		return e.UserID == userID
+	}, role)
+}
+
+func (r *EnrollmentRepo) findActive(keep func(*types.Enrollment) bool, role string) ([]*types.Enrollment, error) {
+	found, err := r.enrollments.find(func(e *types.Enrollment) bool {
+		return e.Status == types.EnrollmentActive && (role == "" || e.Role == role) && keep(e)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].EnrolledAt.Before(found[j].EnrolledAt)
+	})
+	return found, nil
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JustificationRepo struct {
+	justifications *collection[types.Justification]
+}
+
+func NewJustificationRepo() *JustificationRepo {
+	return &JustificationRepo{justifications: newCollection[types.Justification]()}
+}
+
+func setJustificationID(j *types.Justification, id primitive.ObjectID) { j.ID = id }
+
+func (r *JustificationRepo) InsertJustification(Justification *types.Justification) (*repositories.InsertResult, error) {
+	id, err := r.justifications.insert(Justification.ID, Justification, setJustificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *JustificationRepo) FindJustificationByID(JustificationID string) (*types.Justification, error) {
+	id, err := repositories.ParseID(JustificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.justifications.get(id)
+}
+
+func (r *JustificationRepo) GetJustificationsByStudentID(StudentID string) ([]*types.Justification, error) {
+
+	studentID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.find(func(j *types.Justification) bool {
+		return j.StudentID == studentID
+	})
+}
+
+func (r *JustificationRepo) GetJustificationsByCourseID(CourseID string, status string) ([]*types.Justification, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.find(func(j *types.Justification) bool {
+		return j.CourseID == courseID && (status == "" || j.Status == status)
+	})
+}
+
+// find returns the matching justifications, newest first.
+func (r *JustificationRepo) find(keep func(*types.Justification) bool) ([]*types.Justification, error) {
+	found, err := r.justifications.find(keep)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].CreatedAt.After(found[j].CreatedAt) })
+	return found, nil
+}
+
+func (r *JustificationRepo) ReviewJustification(JustificationID string, status string, reviewer primitive.ObjectID, comment string, at time.Time) (bool, error) {
+	id, err := repositories.ParseID(JustificationID)
+	if err != nil {
+		return false, err
+	}
+
+	modified := r.justifications.updateWhere(func(j *types.Justification) bool {
+		return j.ID == id && j.Status == types.JustificationPending
+	}, func(j *types.Justification) bool {
+		j.Status = status
+		j.ReviewedBy = &reviewer
+		j.ReviewedAt = &at
+		j.ReviewComment = comment
+		return true
+	})
+
+	return modified == 1, nil
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"money-minder/internal/types"
+	"sync"
+	"time"
+)
+
+type LeaseRepo struct {
+	mu     sync.Mutex
+	leases map[string]types.Lease
+}
+
+func NewLeaseRepo() *LeaseRepo {
+	return &LeaseRepo{leases: make(map[string]types.Lease)}
+}
+
+func (r *LeaseRepo) AcquireLease(name string, holder string, now time.Time, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease, ok := r.leases[name]
+	if ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+
+	r.leases[name] = types.Lease{ID: name, Holder: holder, ExpiresAt: until}
+	return true, nil
+}
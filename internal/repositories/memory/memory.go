@@ -0,0 +1,212 @@
+// Package memory implements the repositories interfaces on top of plain Go
+// maps so the API can run without a MongoDB instance. Documents are copied
+// through BSON on every read and write, which gives the same isolation and
+// field semantics (omitempty, embedded snapshots) as the Mongo backend.
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"money-minder/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func NewStore() *repositories.Store {
+	return &repositories.Store{
+		Students:        NewStudentRepo(),
+		Teachers:        NewTeacherRepo(),
+		Admins:          NewAdminRepo(),
+		Courses:         NewCourseRepo(),
+		Enrollments:     NewEnrollmentRepo(),
+		Attendances:     NewAttendanceRepo(),
+		ClassSessions:   NewClassSessionRepo(),
+		CheckInWindows:  NewCheckInWindowRepo(),
+		CheckInAttempts: NewCheckInAttemptRepo(),
+		Sessions:        NewSessionRepo(),
+		Justifications:  NewJustificationRepo(),
+		RiskAssessments: NewRiskAssessmentRepo(),
+		Leases:          NewLeaseRepo(),
+	}
+}
+
+// collection is an insertion-ordered set of documents keyed by _id.
+type collection[T any] struct {
+	mu     sync.RWMutex
+	ids    []primitive.ObjectID
+	docs   map[primitive.ObjectID]*T
+	unique []uniqueKey[T]
+}
+
+// uniqueKey mirrors a unique index: it returns the indexed value of a
+// document, or false for documents a partial index leaves out. Like the
+// indexes declared in the database package, it is only checked on insert
+// since no update changes an indexed field.
+type uniqueKey[T any] func(*T) (string, bool)
+
+func newCollection[T any](unique ...uniqueKey[T]) *collection[T] {
+	return &collection[T]{docs: make(map[primitive.ObjectID]*T), unique: unique}
+}
+
+// insert stores a copy of doc under id, generating one when id is zero.
+func (c *collection[T]) insert(id primitive.ObjectID, doc *T, setID func(*T, primitive.ObjectID)) (primitive.ObjectID, error) {
+	stored, err := clone(doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+	}
+	if _, ok := c.docs[id]; ok {
+		return primitive.NilObjectID, fmt.Errorf("%w: _id %s already exists", repositories.ErrDuplicateKey, id.Hex())
+	}
+	for _, key := range c.unique {
+		value, ok := key(stored)
+		if !ok {
+			continue
+		}
+		for _, existing := range c.docs {
+			if other, ok := key(existing); ok && other == value {
+				return primitive.NilObjectID, fmt.Errorf("%w: %s already exists", repositories.ErrDuplicateKey, value)
+			}
+		}
+	}
+
+	setID(stored, id)
+	c.ids = append(c.ids, id)
+	c.docs[id] = stored
+
+	return id, nil
+}
+
+func (c *collection[T]) get(id primitive.ObjectID) (*T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	doc, ok := c.docs[id]
+	if !ok {
+		return nil, nil
+	}
+	return clone(doc)
+}
+
+// find returns copies of every document matching keep, in insertion order.
+func (c *collection[T]) find(keep func(*T) bool) ([]*T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var found []*T
+	for _, id := range c.ids {
+		doc := c.docs[id]
+		if keep != nil && !keep(doc) {
+			continue
+		}
+		cp, err := clone(doc)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, cp)
+	}
+	return found, nil
+}
+
+// update applies fn to the stored document. Like UpdateOne, a missing
+// document is not an error.
+func (c *collection[T]) update(id primitive.ObjectID, fn func(*T) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	doc, ok := c.docs[id]
+	if !ok {
+		return nil
+	}
+	return fn(doc)
+}
+
+// updateWhere applies fn to every stored document matching match, like
+// UpdateMany, and reports how many documents fn changed.
+func (c *collection[T]) updateWhere(match func(*T) bool, fn func(*T) bool) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var modified int64
+	for _, id := range c.ids {
+		doc := c.docs[id]
+		if match(doc) && fn(doc) {
+			modified++
+		}
+	}
+	return modified
+}
+
+// deleteWhere removes the first document accepted by match and reports how many
+// documents were removed.
+func (c *collection[T]) deleteWhere(match func(primitive.ObjectID, *T) bool) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, id := range c.ids {
+		if match(id, c.docs[id]) {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			delete(c.docs, id)
+			return 1
+		}
+	}
+	return 0
+}
+
+func clone[T any](doc *T) (*T, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var cp T
+	if err := bson.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// sameDocument compares two documents the way an $eq match on an embedded
+// document does: field by field on their BSON encoding.
+func sameDocument(a, b any) bool {
+	da, err := bson.Marshal(a)
+	if err != nil {
+		return false
+	}
+	db, err := bson.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(da, db)
+}
+
+// matchesFilter reports whether every non-empty field of filter encodes to
+// the same value in doc, mirroring a Mongo equality query by example.
+func matchesFilter(doc, filter any) bool {
+	var d, f bson.M
+	fd, err := bson.Marshal(filter)
+	if err != nil {
+		return false
+	}
+	dd, err := bson.Marshal(doc)
+	if err != nil {
+		return false
+	}
+	if bson.Unmarshal(fd, &f) != nil || bson.Unmarshal(dd, &d) != nil {
+		return false
+	}
+	for k, v := range f {
+		if !sameDocument(bson.M{"v": v}, bson.M{"v": d[k]}) {
+			return false
+		}
+	}
+	return true
+}
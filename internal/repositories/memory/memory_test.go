@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUniqueKeys(t *testing.T) {
+	students := NewStudentRepo()
+	if _, err := students.InsertStudent(&types.Student{Name: "Ana", Email: "ana@x.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := students.InsertStudent(&types.Student{Name: "Ana", Email: " ANA@x.com"}); !errors.Is(err, repositories.ErrEmailTaken) {
+		t.Errorf("second student with the same email: got %v, want ErrEmailTaken", err)
+	}
+
+	courses := NewCourseRepo()
+	for i := 0; i < 2; i++ {
+		// Like the partial index on code, courses without a code never
+		// conflict.
+		if _, err := courses.InsertCourse(&types.Course{Name: "No code"}); err != nil {
+			t.Fatalf("course without code %d: %v", i, err)
+		}
+	}
+	if _, err := courses.InsertCourse(&types.Course{Name: "Math", Code: "MAT1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := courses.InsertCourse(&types.Course{Name: "Math again", Code: "MAT1"}); !errors.Is(err, repositories.ErrCourseCodeTaken) {
+		t.Errorf("second course with the same code: got %v, want ErrCourseCodeTaken", err)
+	}
+
+	attendances := NewAttendanceRepo()
+	course, student, session := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	at := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	if _, err := attendances.InsertAttendance(&types.Attendance{CourseID: course, StudentID: student, SessionID: session, Date: at}); err != nil {
+		t.Fatal(err)
+	}
+	dup := &types.Attendance{CourseID: course, StudentID: student, SessionID: session, Date: at.Add(24 * time.Hour)}
+	if _, err := attendances.InsertAttendance(dup); !errors.Is(err, repositories.ErrDuplicateAttendance) {
+		t.Errorf("second attendance for the same session: got %v, want ErrDuplicateAttendance", err)
+	}
+
+	// Records without a session are one per day in the course's timezone,
+	// whatever the time; each session of the day gets its own.
+	buenosAires := time.FixedZone("ART", -3*60*60)
+	manual := &types.Attendance{CourseID: course, StudentID: student, Date: at}
+	manual.SetDay(buenosAires)
+	if _, err := attendances.InsertAttendance(manual); err != nil {
+		t.Fatal(err)
+	}
+	sameDay := &types.Attendance{CourseID: course, StudentID: student, Date: at.Add(12 * time.Hour)}
+	sameDay.SetDay(buenosAires)
+	if _, err := attendances.InsertAttendance(sameDay); !errors.Is(err, repositories.ErrDuplicateAttendance) {
+		t.Errorf("second attendance on %s: got %v, want ErrDuplicateAttendance", sameDay.Day, err)
+	}
+	nextDay := &types.Attendance{CourseID: course, StudentID: student, Date: at.Add(18 * time.Hour)}
+	nextDay.SetDay(buenosAires)
+	if _, err := attendances.InsertAttendance(nextDay); err != nil {
+		t.Errorf("attendance on %s: %v", nextDay.Day, err)
+	}
+	otherSession := &types.Attendance{CourseID: course, StudentID: student, SessionID: primitive.NewObjectID(), Date: at.Add(time.Hour)}
+	otherSession.SetDay(buenosAires)
+	if _, err := attendances.InsertAttendance(otherSession); err != nil {
+		t.Errorf("attendance for a second session on %s: %v", otherSession.Day, err)
+	}
+
+	// A rejected insert leaves nothing behind.
+	all, err := students.FindAllStudents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("got %d students after a rejected insert, want 1", len(all))
+	}
+}
+
+func TestInsertKeepsExplicitID(t *testing.T) {
+	students := NewStudentRepo()
+	id := primitive.NewObjectID()
+	res, err := students.InsertStudent(&types.Student{ID: id, Email: "a@x.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.InsertedID != id {
+		t.Errorf("InsertedID = %v, want %v", res.InsertedID, id)
+	}
+	if _, err := students.InsertStudent(&types.Student{ID: id, Email: "b@x.com"}); !errors.Is(err, repositories.ErrEmailTaken) {
+		t.Errorf("second student with the same _id: got %v, want a duplicate key conflict", err)
+	}
+}
+
+func TestListStudentsPages(t *testing.T) {
+	students := NewStudentRepo()
+	for _, usr := range []types.Student{
+		{Name: "Carla", Email: "c@x.com"},
+		{Name: "Ana", Email: "a1@x.com"},
+		{Name: "Bruno", Email: "b@x.com"},
+		{Name: "Ana", Email: "a2@x.com"},
+		{Name: "Diego", Email: "d@x.com"},
+	} {
+		if _, err := students.InsertStudent(&usr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		q    repositories.Query
+		want []string
+	}{
+		{"by name", repositories.Query{Sort: "name"}, []string{"a1@x.com", "a2@x.com", "b@x.com", "c@x.com", "d@x.com"}},
+		{"by name descending", repositories.Query{Sort: "name", Desc: true}, []string{"d@x.com", "c@x.com", "b@x.com", "a2@x.com", "a1@x.com"}},
+		{"by _id", repositories.Query{}, []string{"c@x.com", "a1@x.com", "b@x.com", "a2@x.com", "d@x.com"}},
+		{"search", repositories.Query{Sort: "email", Search: "ANA"}, []string{"a1@x.com", "a2@x.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.q
+			q.Limit = 2
+
+			var got []string
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatal("pagination does not end")
+				}
+				page, err := students.ListStudents(nil, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page.Items) > q.Limit {
+					t.Fatalf("page has %d items, limit is %d", len(page.Items), q.Limit)
+				}
+				for _, usr := range page.Items {
+					got = append(got, usr.Email)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				if q.After, err = repositories.DecodeCursor(page.NextCursor); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		page, err := students.ListStudents(nil, repositories.Query{Sort: "name", Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := repositories.DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := students.ListStudents(nil, repositories.Query{Sort: "email", After: after}); !errors.Is(err, repositories.ErrInvalidCursor) {
+			t.Errorf("got %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestMatchesFilter(t *testing.T) {
+	id := primitive.NewObjectID()
+	doc := &types.Student{ID: id, Name: "Ana", Email: "ana@x.com", Number: "42"}
+
+	tests := []struct {
+		name   string
+		filter any
+		want   bool
+	}{
+		{"empty filter", bson.M{}, true},
+		{"same field", bson.M{"email": "ana@x.com"}, true},
+		{"different field", bson.M{"email": "bea@x.com"}, false},
+		{"one of two fields differs", bson.M{"name": "Ana", "student_number": "43"}, false},
+		{"missing field", bson.M{"status": "active"}, false},
+		{"whole document", &types.Student{ID: id, Name: "Ana", Email: "ana@x.com", Number: "42"}, true},
+		{"omitempty field left out", &types.Student{ID: id, Name: "Ana", Email: "ana@x.com"}, true},
+		{"other id", &types.Student{ID: primitive.NewObjectID(), Name: "Ana", Email: "ana@x.com"}, false},
+		// Name has no omitempty, so a struct filter always compares it,
+		// like the same filter sent to MongoDB.
+		{"zero field without omitempty", &types.Student{Email: "ana@x.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(doc, tt.filter); got != tt.want {
+				t.Errorf("matchesFilter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
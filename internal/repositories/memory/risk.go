@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RiskAssessmentRepo struct {
+	// mu makes the replace-or-insert in SaveRiskAssessment atomic.
+	mu          sync.Mutex
+	assessments *collection[types.RiskAssessment]
+}
+
+func NewRiskAssessmentRepo() *RiskAssessmentRepo {
+	return &RiskAssessmentRepo{assessments: newCollection[types.RiskAssessment]()}
+}
+
+func setRiskAssessmentID(a *types.RiskAssessment, id primitive.ObjectID) { a.ID = id }
+
+func (r *RiskAssessmentRepo) GetRiskAssessmentsByCourseID(CourseID string) ([]*types.RiskAssessment, error) {
+
+	courseID, err := repositories.ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.assessments.find(func(a *types.RiskAssessment) bool {
+		return a.CourseID == courseID
+	})
+}
+
+func (r *RiskAssessmentRepo) GetRiskAssessmentsByStudentID(StudentID string) ([]*types.RiskAssessment, error) {
+
+	studentID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.assessments.find(func(a *types.RiskAssessment) bool {
+		return a.StudentID == studentID
+	})
+}
+
+func (r *RiskAssessmentRepo) SaveRiskAssessment(Assessment *types.RiskAssessment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replacement, err := clone(Assessment)
+	if err != nil {
+		return err
+	}
+
+	replaced := r.assessments.updateWhere(func(a *types.RiskAssessment) bool {
+		return a.CourseID == Assessment.CourseID && a.StudentID == Assessment.StudentID
+	}, func(a *types.RiskAssessment) bool {
+		replacement.ID = a.ID
+		*a = *replacement
+		return true
+	})
+	if replaced > 0 {
+		return nil
+	}
+
+	_, err = r.assessments.insert(primitive.NilObjectID, Assessment, setRiskAssessmentID)
+	return err
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionRepo struct {
+	sessions *collection[types.Session]
+}
+
+func NewSessionRepo() *SessionRepo {
+	return &SessionRepo{sessions: newCollection[types.Session]()}
+}
+
+func setSessionID(s *types.Session, id primitive.ObjectID) { s.ID = id }
+
+func (r *SessionRepo) InsertSession(session *types.Session) (*repositories.InsertResult, error) {
+	id, err := r.sessions.insert(session.ID, session, setSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *SessionRepo) FindSessionByTokenHash(hash string) (*types.Session, error) {
+	found, err := r.sessions.find(func(s *types.Session) bool {
+		return s.TokenHash == hash
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *SessionRepo) MarkSessionUsed(SessionID primitive.ObjectID, usedAt time.Time) (bool, error) {
+	modified := r.sessions.updateWhere(func(s *types.Session) bool {
+		return s.ID == SessionID && s.UsedAt == nil && s.RevokedAt == nil
+	}, func(s *types.Session) bool {
+		s.UsedAt = &usedAt
+		return true
+	})
+
+	return modified == 1, nil
+}
+
+func (r *SessionRepo) IsFamilyActive(FamilyID primitive.ObjectID) (bool, error) {
+	found, err := r.sessions.find(func(s *types.Session) bool {
+		return s.FamilyID == FamilyID && s.RevokedAt == nil
+	})
+	return len(found) > 0, err
+}
+
+func (r *SessionRepo) RevokeFamily(FamilyID primitive.ObjectID) error {
+	r.revoke(func(s *types.Session) bool { return s.FamilyID == FamilyID })
+	return nil
+}
+
+func (r *SessionRepo) RevokeUserSessions(UserID primitive.ObjectID) error {
+	r.revoke(func(s *types.Session) bool { return s.UserID == UserID })
+	return nil
+}
+
+func (r *SessionRepo) revoke(match func(*types.Session) bool) {
+	now := time.Now()
+	r.sessions.updateWhere(func(s *types.Session) bool {
+		return match(s) && s.RevokedAt == nil
+	}, func(s *types.Session) bool {
+		s.RevokedAt = &now
+		return true
+	})
+}
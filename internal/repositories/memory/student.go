@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"fmt"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StudentRepo struct {
+	students *collection[types.Student]
+}
+
+func NewStudentRepo() *StudentRepo {
+	return &StudentRepo{students: newCollection(func(usr *types.Student) (string, bool) {
+		return usr.Email, true
+	})}
+}
+
+func setStudentID(usr *types.Student, id primitive.ObjectID) { usr.ID = id }
+
+func (r *StudentRepo) InsertStudent(usr *types.Student) (*repositories.InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
+	id, err := r.students.insert(usr.ID, usr, setStudentID)
+	if err != nil {
+		return nil, repositories.AsConflict(err, repositories.ErrEmailTaken)
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *StudentRepo) DeleteStudent(usr *types.Student) (*repositories.DeleteResult, error) {
+	deleted := r.students.deleteWhere(func(_ primitive.ObjectID, doc *types.Student) bool {
+		return matchesFilter(doc, usr)
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *StudentRepo) DeleteStudentByID(usrID string) (*repositories.DeleteResult, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := r.students.deleteWhere(func(docID primitive.ObjectID, _ *types.Student) bool {
+		return docID == id
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *StudentRepo) FindStudentByID(usrID string) (*types.Student, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.students.get(id)
+}
+
+func (r *StudentRepo) FindStudentByEmail(email string) (*types.Student, error) {
+	email = types.NormalizeEmail(email)
+	found, err := r.students.find(func(usr *types.Student) bool {
+		return usr.Email == email
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *StudentRepo) FindAllStudents() ([]types.Student, error) {
+	found, err := r.students.find(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var usrs []types.Student
+	for _, usr := range found {
+		usrs = append(usrs, *usr)
+	}
+	return usrs, nil
+}
+
+func (r *StudentRepo) FindStudentsByIDs(ids []primitive.ObjectID) ([]*types.Student, error) {
+	wanted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	return r.students.find(func(usr *types.Student) bool {
+		return wanted[usr.ID]
+	})
+}
+
+func (r *StudentRepo) ListStudents(ids []primitive.ObjectID, q repositories.Query) (*repositories.Page[*types.Student], error) {
+	var wanted map[primitive.ObjectID]bool
+	if ids != nil {
+		wanted = make(map[primitive.ObjectID]bool, len(ids))
+		for _, id := range ids {
+			wanted[id] = true
+		}
+	}
+
+	found, err := r.students.find(func(usr *types.Student) bool {
+		return (wanted == nil || wanted[usr.ID]) && q.Matches(usr.Name, usr.Email) && q.HasAccountStatus(usr.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.PageOf(found, q, repositories.StudentSorts, func(usr *types.Student) primitive.ObjectID { return usr.ID })
+}
+
+func (r *StudentRepo) AddAttendance(StudentID string, AttendanceID string, AttendanceRepo repositories.AttendanceRepository) error {
+
+	StudentObjID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return fmt.Errorf("invalid Student ID: %w", err)
+	}
+
+	Attendance, err := AttendanceRepo.FindAttendanceByID(AttendanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find Attendance: %w", err)
+	}
+	if Attendance == nil {
+		return fmt.Errorf("Attendance not found")
+	}
+
+	return r.students.update(StudentObjID, func(usr *types.Student) error {
+		usr.Attendances = append(usr.Attendances, *Attendance)
+		return nil
+	})
+}
+
+func (r *StudentRepo) RemoveAttendance(StudentID string, AttendanceID string, AttendanceRepo repositories.AttendanceRepository) error {
+
+	StudentObjectID, err := repositories.ParseID(StudentID)
+	if err != nil {
+		return fmt.Errorf("invalid Student ID: %w", err)
+	}
+
+	Attendance, err := AttendanceRepo.FindAttendanceByID(AttendanceID)
+	if err != nil {
+		return fmt.Errorf("failed to find Attendance: %w", err)
+	}
+	if Attendance == nil {
+		return fmt.Errorf("Attendance not found")
+	}
+
+	return r.students.update(StudentObjectID, func(usr *types.Student) error {
+		usr.Attendances = pull(usr.Attendances, *Attendance)
+		return nil
+	})
+}
+
+// pull removes every element equal to v, like a $pull with $eq.
+func pull[T any](items []T, v T) []T {
+	var kept []T
+	for _, item := range items {
+		if !sameDocument(item, v) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func (r *StudentRepo) SetStatus(usrID string, status string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.students.update(id, func(usr *types.Student) error {
+		usr.Status = status
+		return nil
+	})
+}
+
+func (r *StudentRepo) UpdatePassword(usrID string, hash string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.students.update(id, func(usr *types.Student) error {
+		usr.Password = hash
+		return nil
+	})
+}
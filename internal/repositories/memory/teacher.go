@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TeacherRepo struct {
+	teachers *collection[types.Teacher]
+}
+
+func NewTeacherRepo() *TeacherRepo {
+	return &TeacherRepo{teachers: newCollection(func(usr *types.Teacher) (string, bool) {
+		return usr.Email, true
+	})}
+}
+
+func setTeacherID(usr *types.Teacher, id primitive.ObjectID) { usr.ID = id }
+
+func (r *TeacherRepo) InsertTeacher(usr *types.Teacher) (*repositories.InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
+	id, err := r.teachers.insert(usr.ID, usr, setTeacherID)
+	if err != nil {
+		return nil, repositories.AsConflict(err, repositories.ErrEmailTaken)
+	}
+
+	return &repositories.InsertResult{InsertedID: id}, nil
+}
+
+func (r *TeacherRepo) DeleteTeacher(usr *types.Teacher) (*repositories.DeleteResult, error) {
+	deleted := r.teachers.deleteWhere(func(_ primitive.ObjectID, doc *types.Teacher) bool {
+		return matchesFilter(doc, usr)
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *TeacherRepo) DeleteTeacherByID(usrID string) (*repositories.DeleteResult, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := r.teachers.deleteWhere(func(docID primitive.ObjectID, _ *types.Teacher) bool {
+		return docID == id
+	})
+
+	return &repositories.DeleteResult{DeletedCount: deleted}, nil
+}
+
+func (r *TeacherRepo) FindTeacherByID(usrID string) (*types.Teacher, error) {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.teachers.get(id)
+}
+
+func (r *TeacherRepo) FindTeacherByEmail(email string) (*types.Teacher, error) {
+	email = types.NormalizeEmail(email)
+	found, err := r.teachers.find(func(usr *types.Teacher) bool {
+		return usr.Email == email
+	})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return found[0], nil
+}
+
+func (r *TeacherRepo) FindAllTeachers() ([]types.Teacher, error) {
+	found, err := r.teachers.find(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var usrs []types.Teacher
+	for _, usr := range found {
+		usrs = append(usrs, *usr)
+	}
+	return usrs, nil
+}
+
+func (r *TeacherRepo) ListTeachers(q repositories.Query) (*repositories.Page[*types.Teacher], error) {
+	found, err := r.teachers.find(func(usr *types.Teacher) bool {
+		return q.Matches(usr.Name, usr.Email) && q.HasAccountStatus(usr.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.PageOf(found, q, repositories.TeacherSorts, func(usr *types.Teacher) primitive.ObjectID { return usr.ID })
+}
+
+func (r *TeacherRepo) UpdateName(usrID string, newName string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.teachers.update(id, func(usr *types.Teacher) error {
+		usr.Name = newName
+		return nil
+	})
+}
+
+func (r *TeacherRepo) SetStatus(usrID string, status string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.teachers.update(id, func(usr *types.Teacher) error {
+		usr.Status = status
+		return nil
+	})
+}
+
+func (r *TeacherRepo) UpdatePassword(usrID string, hash string) error {
+	id, err := repositories.ParseID(usrID)
+	if err != nil {
+		return err
+	}
+
+	return r.teachers.update(id, func(usr *types.Teacher) error {
+		usr.Password = hash
+		return nil
+	})
+}
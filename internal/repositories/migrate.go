@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"money-minder/internal/database"
+	"money-minder/internal/schedule"
+	"money-minder/internal/types"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration rewrites existing documents after a schema change. Every
+// migration runs once; applied names are recorded in the migrations
+// collection.
+type migration struct {
+	name string
+	run  func(ctx context.Context, db database.Service) error
+}
+
+var migrations = []migration{
+	{name: "course-legacy-schedules", run: migrateLegacySchedules},
+	{name: "attendance-status", run: migrateAttendanceStatus},
+	{name: "enrollments", run: migrateEnrollments},
+	{name: "lowercase-emails", run: migrateLowercaseEmails},
+	{name: "attendance-day", run: migrateAttendanceDay},
+}
+
+// Migrate applies every migration that has not run yet against db.
+func Migrate(db database.Service) error {
+	ctx := context.Background()
+	applied := db.GetCollection("migrations")
+
+	for _, m := range migrations {
+		err := applied.FindOne(ctx, bson.M{"_id": m.name}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		slog.Info("Running migration", "name", m.name)
+		if err := m.run(ctx, db); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+
+		_, err = applied.InsertOne(ctx, bson.M{"_id": m.name, "applied_at": time.Now().UTC()})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateLegacySchedules moves free-form string schedules out of the way so
+// the schedules field only ever holds ScheduleSlot documents.
+func migrateLegacySchedules(ctx context.Context, db database.Service) error {
+	_, err := db.GetCollection("courses").UpdateMany(ctx,
+		bson.M{"schedules.0": bson.M{"$type": "string"}},
+		bson.M{"$rename": bson.M{"schedules": "legacy_schedules"}},
+	)
+	return err
+}
+
+// migrateAttendanceStatus gives records from before Attendance.Status the
+// status their present flag stood for.
+func migrateAttendanceStatus(ctx context.Context, db database.Service) error {
+	attendances := db.GetCollection("attendances")
+
+	_, err := attendances.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$exists": false}, "present": true},
+		bson.M{"$set": bson.M{"status": types.StatusPresent}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = attendances.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"status": types.StatusAbsent, "present": false}},
+	)
+	return err
+}
+
+// migrateEnrollments turns the student and course copies embedded in
+// courses.students, students.courses and teachers.courses into
+// enrollments, then drops the arrays. A link found on either side counts,
+// so students whose removal silently failed end up enrolled again.
+func migrateEnrollments(ctx context.Context, db database.Service) error {
+	enrollments := &EnrollmentRepo{MongoCollection: db.GetCollection("enrollments")}
+	now := time.Now().UTC()
+
+	links := []struct {
+		collection string
+		field      string
+		role       string
+		courseSide bool
+	}{
+		{collection: "courses", field: "students", role: types.EnrollmentRoleStudent, courseSide: true},
+		{collection: "students", field: "courses", role: types.EnrollmentRoleStudent},
+		{collection: "teachers", field: "courses", role: types.EnrollmentRoleTeacher},
+	}
+
+	for _, link := range links {
+		collection := db.GetCollection(link.collection)
+		filter := bson.M{link.field + ".0": bson.M{"$exists": true}}
+		opts := options.Find().SetProjection(bson.M{link.field + "._id": 1})
+
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			owner, _ := doc["_id"].(primitive.ObjectID)
+			embedded, _ := doc[link.field].(bson.A)
+
+			for _, item := range embedded {
+				embeddedDoc, _ := item.(bson.M)
+				other, ok := embeddedDoc["_id"].(primitive.ObjectID)
+				if !ok || owner.IsZero() {
+					continue
+				}
+
+				CourseID, UserID := other, owner
+				if link.courseSide {
+					CourseID, UserID = owner, other
+				}
+				if _, err := enrollments.Enroll(CourseID, UserID, link.role, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{link.field: ""}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateLowercaseEmails stores every account email in the form
+// types.NormalizeEmail looks it up in. Accounts whose emails differ only in
+// case would end up sharing one, so when there are any nothing is rewritten
+// and the migration fails listing them, for an admin to merge or rename by
+// hand before starting the server again.
+func migrateLowercaseEmails(ctx context.Context, db database.Service) error {
+	type account struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+
+	accounts := make(map[string][]account)
+	var conflicts []string
+	for _, name := range []string{"students", "teachers"} {
+		opts := options.Find().SetProjection(bson.M{"email": 1})
+		cursor, err := db.GetCollection(name).Find(ctx, bson.M{}, opts)
+		if err != nil {
+			return err
+		}
+
+		var docs []account
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+		accounts[name] = docs
+
+		owners := make(map[string][]account, len(docs))
+		for _, doc := range docs {
+			email := types.NormalizeEmail(doc.Email)
+			owners[email] = append(owners[email], doc)
+		}
+		for _, doc := range docs {
+			if same := owners[types.NormalizeEmail(doc.Email)]; len(same) > 1 {
+				conflicts = append(conflicts, fmt.Sprintf("%s %s %q", name, doc.ID.Hex(), doc.Email))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("emails that differ only in case, rename or merge these accounts: %s", strings.Join(conflicts, ", "))
+	}
+
+	for name, docs := range accounts {
+		collection := db.GetCollection(name)
+		for _, doc := range docs {
+			email := types.NormalizeEmail(doc.Email)
+			if email == doc.Email {
+				continue
+			}
+			if _, err := collection.UpdateByID(ctx, doc.ID, bson.M{"$set": bson.M{"email": email}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// migrateAttendanceDay fills the day of every attendance record from its
+// date, in the timezone of its course, or UTC when the course is gone.
+func migrateAttendanceDay(ctx context.Context, db database.Service) error {
+	attendances := db.GetCollection("attendances")
+	setDay := func(filter bson.M, loc *time.Location) error {
+		filter["day"] = bson.M{"$exists": false}
+		_, err := attendances.UpdateMany(ctx, filter, bson.A{bson.M{"$set": bson.M{
+			"day": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$date", "timezone": loc.String()}},
+		}}})
+		return err
+	}
+
+	opts := options.Find().SetProjection(bson.M{"schedules": 1})
+	cursor, err := db.GetCollection("courses").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	var Courses []types.Course
+	if err := cursor.All(ctx, &Courses); err != nil {
+		return err
+	}
+
+	for _, Course := range Courses {
+		if err := setDay(bson.M{"course_id": Course.ID}, schedule.Location(Course.Schedules)); err != nil {
+			return err
+		}
+	}
+	return setDay(bson.M{}, time.UTC)
+}
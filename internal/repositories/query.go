@@ -0,0 +1,377 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Page sizes of list endpoints.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// ErrInvalidCursor is returned for a cursor that was not produced by a
+// previous page of the same list and sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Query selects one page of a list: the filters that apply, the order and
+// where the previous page ended. Filters a list does not support are
+// ignored.
+type Query struct {
+	Limit int
+	// Sort is one of the keys of the list's Sorts; empty sorts by _id.
+	Sort string
+	Desc bool
+	// After is the cursor of the previous page.
+	After *Cursor
+	// Search matches a case-insensitive substring of the list's text
+	// fields, like names and emails.
+	Search string
+	// From and To bound dates, both inclusive, when not zero.
+	From time.Time
+	To   time.Time
+	// Status keeps only attendances or accounts with this status.
+	Status string
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Cursor marks the last item of a page by its sort value and _id, which
+// breaks ties, so pages stay stable while documents are added.
+type Cursor struct {
+	Sort  string             `bson:"s"`
+	Desc  bool               `bson:"d,omitempty"`
+	Value any                `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// Encode returns the opaque form of c handed to clients.
+func (c *Cursor) Encode() string {
+	data, err := bson.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := bson.Unmarshal(data, &c); err != nil || c.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if dt, ok := c.Value.(primitive.DateTime); ok {
+		c.Value = dt.Time().UTC()
+	}
+	return &c, nil
+}
+
+// SortKey is one order a list can be sorted by: the stored field and how
+// to read its value from a document.
+type SortKey[T any] struct {
+	Field string
+	Value func(*T) any
+}
+
+// Sorts are the orders a list accepts, by the name clients use.
+type Sorts[T any] map[string]SortKey[T]
+
+// Names lists the accepted sort names, for error messages.
+func (s Sorts[T]) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var AttendanceSorts = Sorts[types.Attendance]{
+	"date": {Field: "date", Value: func(a *types.Attendance) any { return a.Date.UTC() }},
+}
+
+var StudentSorts = Sorts[types.Student]{
+	"name":  {Field: "name", Value: func(s *types.Student) any { return s.Name }},
+	"email": {Field: "email", Value: func(s *types.Student) any { return s.Email }},
+}
+
+var TeacherSorts = Sorts[types.Teacher]{
+	"name":  {Field: "name", Value: func(t *types.Teacher) any { return t.Name }},
+	"email": {Field: "email", Value: func(t *types.Teacher) any { return t.Email }},
+}
+
+var AdminSorts = Sorts[types.Admin]{
+	"name":  {Field: "name", Value: func(a *types.Admin) any { return a.Name }},
+	"email": {Field: "email", Value: func(a *types.Admin) any { return a.Email }},
+}
+
+var CourseSorts = Sorts[types.Course]{
+	"name": {Field: "name", Value: func(c *types.Course) any { return c.Name }},
+	"code": {Field: "code", Value: func(c *types.Course) any { return c.Code }},
+}
+
+// limit returns the page size q asks for, within bounds.
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return min(q.Limit, MaxLimit)
+}
+
+// key resolves the sort of q and checks that its cursor was made for the
+// same order. The cursor's value goes into the MongoDB filter as it is, so
+// it must have the type the sort key reads, never a document a client could
+// slip an operator into.
+func key[T any](q Query, sorts Sorts[T]) (SortKey[T], error) {
+	k := SortKey[T]{Field: "_id"}
+	if q.Sort != "" {
+		var ok bool
+		if k, ok = sorts[q.Sort]; !ok {
+			return k, fmt.Errorf("unknown sort %q", q.Sort)
+		}
+	}
+	if q.After == nil {
+		return k, nil
+	}
+	if q.After.Sort != q.Sort || q.After.Desc != q.Desc {
+		return k, ErrInvalidCursor
+	}
+
+	var want reflect.Type
+	if k.Value != nil {
+		var zero T
+		want = reflect.TypeOf(k.Value(&zero))
+	}
+	if reflect.TypeOf(q.After.Value) != want {
+		return k, ErrInvalidCursor
+	}
+	return k, nil
+}
+
+// SearchFilter matches documents where any of fields contains q.Search.
+func (q Query) SearchFilter(fields ...string) bson.M {
+	if q.Search == "" {
+		return nil
+	}
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q.Search), Options: "i"}
+	or := make(bson.A, 0, len(fields))
+	for _, field := range fields {
+		or = append(or, bson.M{field: pattern})
+	}
+	return bson.M{"$or": or}
+}
+
+// DateFilter matches documents whose field falls between q.From and q.To.
+func (q Query) DateFilter(field string) bson.M {
+	bounds := bson.M{}
+	if !q.From.IsZero() {
+		bounds["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		bounds["$lte"] = q.To
+	}
+	if len(bounds) == 0 {
+		return nil
+	}
+	return bson.M{field: bounds}
+}
+
+// AccountFilter matches accounts with status q.Status. Accounts stored
+// without a status are active.
+func (q Query) AccountFilter() bson.M {
+	switch q.Status {
+	case "":
+		return nil
+	case types.AccountActive:
+		return bson.M{"status": bson.M{"$in": bson.A{nil, "", types.AccountActive}}}
+	}
+	return bson.M{"status": q.Status}
+}
+
+// findPage runs filter, which may hold nil clauses, and returns the page of
+// results q asks for.
+func findPage[T any](coll *mongo.Collection, q Query, sorts Sorts[T], id func(*T) primitive.ObjectID, filter ...bson.M) (*Page[*T], error) {
+	k, err := key(q, sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	order := 1
+	if q.Desc {
+		order = -1
+	}
+	if q.After != nil {
+		op := "$gt"
+		if q.Desc {
+			op = "$lt"
+		}
+		if k.Field == "_id" {
+			filter = append(filter, bson.M{"_id": bson.M{op: q.After.ID}})
+		} else {
+			filter = append(filter, bson.M{"$or": bson.A{
+				bson.M{k.Field: bson.M{op: q.After.Value}},
+				bson.M{k.Field: q.After.Value, "_id": bson.M{op: q.After.ID}},
+			}})
+		}
+	}
+
+	and := bson.A{}
+	for _, clause := range filter {
+		if clause != nil {
+			and = append(and, clause)
+		}
+	}
+	match := bson.M{}
+	if len(and) > 0 {
+		match["$and"] = and
+	}
+
+	sortDoc := bson.D{{Key: "_id", Value: order}}
+	if k.Field != "_id" {
+		sortDoc = bson.D{{Key: k.Field, Value: order}, {Key: "_id", Value: order}}
+	}
+	limit := q.limit()
+	opts := options.Find().SetSort(sortDoc).SetLimit(int64(limit + 1))
+
+	var Items []*T
+	cursor, err := coll.Find(context.Background(), match, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.Background(), &Items); err != nil {
+		return nil, err
+	}
+
+	return paginate(Items, q, k, limit, id), nil
+}
+
+// PageOf returns the page q asks for out of items, every document of the
+// list already filtered. It is what findPage does in MongoDB, for lists
+// built in memory.
+func PageOf[T any](items []*T, q Query, sorts Sorts[T], id func(*T) primitive.ObjectID) (*Page[*T], error) {
+	k, err := key(q, sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := func(a *T, value any, aid primitive.ObjectID) int {
+		c := 0
+		if k.Value != nil {
+			c = compareValues(k.Value(a), value)
+		}
+		if c == 0 {
+			c = compareValues(id(a), aid)
+		}
+		if q.Desc {
+			c = -c
+		}
+		return c
+	}
+
+	sorted := append([]*T(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		var value any
+		if k.Value != nil {
+			value = k.Value(sorted[j])
+		}
+		return compare(sorted[i], value, id(sorted[j])) < 0
+	})
+
+	if q.After != nil {
+		start := sort.Search(len(sorted), func(i int) bool {
+			return compare(sorted[i], q.After.Value, q.After.ID) > 0
+		})
+		sorted = sorted[start:]
+	}
+
+	limit := q.limit()
+	if len(sorted) > limit+1 {
+		sorted = sorted[:limit+1]
+	}
+	return paginate(sorted, q, k, limit, id), nil
+}
+
+// paginate trims items, fetched one past the page size, to the page and
+// sets the cursor when there is a next page.
+func paginate[T any](items []*T, q Query, k SortKey[T], limit int, id func(*T) primitive.ObjectID) *Page[*T] {
+	page := &Page[*T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		next := &Cursor{Sort: q.Sort, Desc: q.Desc, ID: id(last)}
+		if k.Value != nil {
+			next.Value = k.Value(last)
+		}
+		page.NextCursor = next.Encode()
+	}
+	if page.Items == nil {
+		page.Items = []*T{}
+	}
+	return page
+}
+
+// compareValues orders two sort values of the same kind the way MongoDB
+// does.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	case primitive.ObjectID:
+		b, _ := b.(primitive.ObjectID)
+		return strings.Compare(a.Hex(), b.Hex())
+	}
+	return 0
+}
+
+// Matches reports whether any of values contains q.Search, ignoring case,
+// like SearchFilter.
+func (q Query) Matches(values ...string) bool {
+	if q.Search == "" {
+		return true
+	}
+	search := strings.ToLower(q.Search)
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAccountStatus reports whether an account stored with status matches
+// q.Status, like AccountFilter.
+func (q Query) HasAccountStatus(status string) bool {
+	return q.Status == "" || types.AccountStatus(status) == q.Status
+}
+
+// InRange reports whether t falls between q.From and q.To, like DateFilter.
+func (q Query) InRange(t time.Time) bool {
+	return (q.From.IsZero() || !t.Before(q.From)) && (q.To.IsZero() || !t.After(q.To))
+}
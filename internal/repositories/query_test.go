@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// walk follows the cursors of q until the last page and returns the name
+// of every item, pages separated by "|".
+func walk[T any](t *testing.T, items []*T, q Query, sorts Sorts[T], id func(*T) primitive.ObjectID, name func(*T) string) string {
+	t.Helper()
+
+	var pages []string
+	for {
+		page, err := PageOf(items, q, sorts, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range page.Items {
+			names = append(names, name(item))
+		}
+		pages = append(pages, strings.Join(names, ","))
+
+		if page.NextCursor == "" {
+			return strings.Join(pages, "|")
+		}
+		if q.After, err = DecodeCursor(page.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	// Two students share a name, so _id has to break the tie.
+	var students []*types.Student
+	for _, name := range []string{"Dora", "Ana", "Carla", "Ana", "Bea"} {
+		students = append(students, &types.Student{ID: primitive.NewObjectID(), Name: name})
+	}
+	studentName := func(s *types.Student) string { return s.Name }
+
+	tests := []struct {
+		q    Query
+		want string
+	}{
+		{q: Query{Limit: 2}, want: "Dora,Ana|Carla,Ana|Bea"},
+		{q: Query{Limit: 2, Desc: true}, want: "Bea,Ana|Carla,Ana|Dora"},
+		{q: Query{Limit: 2, Sort: "name"}, want: "Ana,Ana|Bea,Carla|Dora"},
+		{q: Query{Limit: 3, Sort: "name", Desc: true}, want: "Dora,Carla,Bea|Ana,Ana"},
+		{q: Query{Limit: 5, Sort: "name"}, want: "Ana,Ana,Bea,Carla,Dora"},
+	}
+	for _, tt := range tests {
+		if got := walk(t, students, tt.q, StudentSorts, studentID, studentName); got != tt.want {
+			t.Errorf("sort %q desc %v limit %d: got %s, want %s", tt.q.Sort, tt.q.Desc, tt.q.Limit, got, tt.want)
+		}
+	}
+
+	// Dates come back from the cursor as the time.Time the sort key reads.
+	day := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	var attendances []*types.Attendance
+	for _, offset := range []int{2, 0, 1} {
+		attendances = append(attendances, &types.Attendance{ID: primitive.NewObjectID(), Date: day.AddDate(0, 0, offset)})
+	}
+	got := walk(t, attendances, Query{Limit: 1, Sort: "date"}, AttendanceSorts, attendanceID, func(a *types.Attendance) string {
+		return a.Date.Format(time.DateOnly)
+	})
+	if want := "2024-03-04|2024-03-05|2024-03-06"; got != want {
+		t.Errorf("by date: got %s, want %s", got, want)
+	}
+}
+
+func TestCursorMustMatchSort(t *testing.T) {
+	students := []*types.Student{{ID: primitive.NewObjectID(), Name: "Ana"}}
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		q      Query
+		cursor Cursor
+	}{
+		{name: "other sort", q: Query{Sort: "name"}, cursor: Cursor{Sort: "email", Value: "a@x.com", ID: id}},
+		{name: "other direction", q: Query{Sort: "name"}, cursor: Cursor{Sort: "name", Desc: true, Value: "Ana", ID: id}},
+		{name: "operator document", q: Query{Sort: "name"}, cursor: Cursor{Sort: "name", Value: bson.M{"$ne": ""}, ID: id}},
+		{name: "date for a name", q: Query{Sort: "name"}, cursor: Cursor{Sort: "name", Value: time.Now(), ID: id}},
+		{name: "missing value", q: Query{Sort: "name"}, cursor: Cursor{Sort: "name", ID: id}},
+		{name: "value with _id", q: Query{}, cursor: Cursor{Value: bson.M{"$gt": ""}, ID: id}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := DecodeCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.q.After = after
+			if _, err := PageOf(students, tt.q, StudentSorts, studentID); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "not base64!", "aGVsbG8"} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q): got %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"money-minder/internal/database"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsertResult keeps the same JSON shape as mongo.InsertOneResult so
+// handlers respond identically regardless of the storage backend.
+type InsertResult struct {
+	InsertedID primitive.ObjectID
+}
+
+// DeleteResult keeps the same JSON shape as mongo.DeleteResult.
+type DeleteResult struct {
+	DeletedCount int64
+}
+
+type StudentRepository interface {
+	InsertStudent(usr *types.Student) (*InsertResult, error)
+	DeleteStudent(usr *types.Student) (*DeleteResult, error)
+	DeleteStudentByID(usrID string) (*DeleteResult, error)
+	FindStudentByID(usrID string) (*types.Student, error)
+	FindStudentByEmail(email string) (*types.Student, error)
+	FindAllStudents() ([]types.Student, error)
+	FindStudentsByIDs(ids []primitive.ObjectID) ([]*types.Student, error)
+	// ListStudents pages through students, only those in ids when it is
+	// not nil. Search matches name and email; Status is the account status.
+	ListStudents(ids []primitive.ObjectID, q Query) (*Page[*types.Student], error)
+	SetStatus(usrID string, status string) error
+	UpdatePassword(usrID string, hash string) error
+	AddAttendance(StudentID string, AttendanceID string, AttendanceRepo AttendanceRepository) error
+	RemoveAttendance(StudentID string, AttendanceID string, AttendanceRepo AttendanceRepository) error
+}
+
+type TeacherRepository interface {
+	InsertTeacher(usr *types.Teacher) (*InsertResult, error)
+	DeleteTeacher(usr *types.Teacher) (*DeleteResult, error)
+	DeleteTeacherByID(usrID string) (*DeleteResult, error)
+	FindTeacherByID(usrID string) (*types.Teacher, error)
+	FindTeacherByEmail(email string) (*types.Teacher, error)
+	FindAllTeachers() ([]types.Teacher, error)
+	ListTeachers(q Query) (*Page[*types.Teacher], error)
+	UpdateName(usrID string, newName string) error
+	SetStatus(usrID string, status string) error
+	UpdatePassword(usrID string, hash string) error
+}
+
+// AdminRepository stores the accounts that manage every other account.
+type AdminRepository interface {
+	InsertAdmin(usr *types.Admin) (*InsertResult, error)
+	DeleteAdminByID(usrID string) (*DeleteResult, error)
+	FindAdminByID(usrID string) (*types.Admin, error)
+	FindAdminByEmail(email string) (*types.Admin, error)
+	CountAdmins() (int64, error)
+	// ListAdmins pages through admins. Search matches name and email.
+	ListAdmins(q Query) (*Page[*types.Admin], error)
+	SetStatus(usrID string, status string) error
+	UpdatePassword(usrID string, hash string) error
+}
+
+type CourseRepository interface {
+	InsertCourse(Course *types.Course) (*InsertResult, error)
+	DeleteCourse(CourseID string) (*DeleteResult, error)
+	FindCourseByID(CourseID string) (*types.Course, error)
+	FindAllCourses() ([]types.Course, error)
+	FindCoursesByIDs(ids []primitive.ObjectID) ([]*types.Course, error)
+	// ListCourses pages through every course. Search matches name and code.
+	ListCourses(q Query) (*Page[*types.Course], error)
+	UpdateName(CourseID string, newName string) error
+	UpdateTeacher(CourseID string, newTeacherID string) error
+	GetCoursesByTeacherID(TeacherID string) ([]*types.Course, error)
+	UpdateLocation(CourseID string, location *types.GeoFence) error
+	UpdateSchedules(CourseID string, schedules []types.ScheduleSlot) error
+	UpdatePolicy(CourseID string, policy *types.AttendancePolicy) error
+}
+
+type AttendanceRepository interface {
+	InsertAttendance(Attendance *types.Attendance) (*InsertResult, error)
+	DeleteAttendance(AttendanceID string) (*DeleteResult, error)
+	UpdateStatus(AttendanceID string, Attendance *types.Attendance) error
+	FindAttendanceByID(AttendanceID string) (*types.Attendance, error)
+	GetAttendancesByCourseID(id string) ([]*types.Attendance, error)
+	// EachAttendanceInRange calls fn with the attendances of a course dated
+	// between q.From and q.To, ordered by student and date, one at a time.
+	EachAttendanceInRange(CourseID string, q Query, fn func(*types.Attendance) error) error
+	// GetUnsessionedAttendanceDates returns the distinct dates of the
+	// attendances of a course taken without a class session, between
+	// q.From and q.To.
+	GetUnsessionedAttendanceDates(CourseID string, q Query) ([]time.Time, error)
+	GetAttendancesByStudentID(StudentID string) ([]*types.Attendance, error)
+	// ListAttendancesByCourseID and ListAttendancesByStudentID page through
+	// the attendances of a course or student, filtered by date and status.
+	ListAttendancesByCourseID(CourseID string, q Query) (*Page[*types.Attendance], error)
+	ListAttendancesByStudentID(StudentID string, q Query) (*Page[*types.Attendance], error)
+	GetAttendancesBySessionID(SessionID string) ([]*types.Attendance, error)
+	FindSessionAttendance(SessionID string, StudentID string) (*types.Attendance, error)
+	RecordCheckIn(AttendanceID string, checkIn *types.Attendance) error
+	ReviewGeoCheck(AttendanceID string, approved bool, reviewer primitive.ObjectID, at time.Time) error
+	GetFlaggedAttendancesByCourseID(CourseID string) ([]*types.Attendance, error)
+	ExcuseAbsences(CourseID primitive.ObjectID, StudentID primitive.ObjectID, from time.Time, until time.Time) (int64, error)
+	CountAttendancesByStudent(CourseID string) ([]types.AttendanceSummary, error)
+}
+
+// EnrollmentRepository keeps who takes or teaches each course. Enroll and
+// Unenroll report whether they changed anything, so enrolling twice or
+// dropping a student who is not enrolled is detectable.
+type EnrollmentRepository interface {
+	Enroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error)
+	Unenroll(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string, at time.Time) (bool, error)
+	IsEnrolled(CourseID primitive.ObjectID, UserID primitive.ObjectID, role string) (bool, error)
+	GetEnrollmentsByCourseID(CourseID string, role string) ([]*types.Enrollment, error)
+	GetEnrollmentsByUserID(UserID string, role string) ([]*types.Enrollment, error)
+}
+
+type ClassSessionRepository interface {
+	InsertClassSession(ClassSession *types.ClassSession) (*InsertResult, error)
+	FindClassSessionByID(ClassSessionID string) (*types.ClassSession, error)
+	GetClassSessionsByCourseID(CourseID string) ([]*types.ClassSession, error)
+	// GetClassSessionsInRange returns the sessions of a course that started
+	// between q.From and q.To, ordered by start time.
+	GetClassSessionsInRange(CourseID string, q Query) ([]*types.ClassSession, error)
+	CloseClassSession(ClassSessionID string, end time.Time) error
+	GetClassSessionsAwaitingAbsences(endedBefore time.Time) ([]*types.ClassSession, error)
+	MarkAbsencesRecorded(ClassSessionID string, at time.Time) error
+	CountClassSessionsHeld(CourseID string, before time.Time) (int64, error)
+}
+
+type CheckInWindowRepository interface {
+	InsertCheckInWindow(Window *types.CheckInWindow) (*InsertResult, error)
+	FindCheckInWindowByID(WindowID string) (*types.CheckInWindow, error)
+	CloseCheckInWindow(WindowID string, closedAt time.Time) error
+	SetCheckInCode(WindowID string, codeHash string, expiresAt time.Time) error
+	GetOpenCheckInWindowsByCourseID(CourseID string, t time.Time) ([]*types.CheckInWindow, error)
+}
+
+type CheckInAttemptRepository interface {
+	FindCheckInAttempt(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error)
+	IncrementCheckInFailures(WindowID primitive.ObjectID, StudentID primitive.ObjectID) (*types.CheckInAttempt, error)
+	LockCheckInAttempts(WindowID primitive.ObjectID, StudentID primitive.ObjectID, until time.Time) error
+}
+
+type SessionRepository interface {
+	InsertSession(session *types.Session) (*InsertResult, error)
+	FindSessionByTokenHash(hash string) (*types.Session, error)
+	MarkSessionUsed(SessionID primitive.ObjectID, usedAt time.Time) (bool, error)
+	IsFamilyActive(FamilyID primitive.ObjectID) (bool, error)
+	RevokeFamily(FamilyID primitive.ObjectID) error
+	RevokeUserSessions(UserID primitive.ObjectID) error
+}
+
+type JustificationRepository interface {
+	InsertJustification(Justification *types.Justification) (*InsertResult, error)
+	FindJustificationByID(JustificationID string) (*types.Justification, error)
+	GetJustificationsByStudentID(StudentID string) ([]*types.Justification, error)
+	GetJustificationsByCourseID(CourseID string, status string) ([]*types.Justification, error)
+	ReviewJustification(JustificationID string, status string, reviewer primitive.ObjectID, comment string, at time.Time) (bool, error)
+}
+
+type RiskAssessmentRepository interface {
+	GetRiskAssessmentsByCourseID(CourseID string) ([]*types.RiskAssessment, error)
+	GetRiskAssessmentsByStudentID(StudentID string) ([]*types.RiskAssessment, error)
+	SaveRiskAssessment(Assessment *types.RiskAssessment) error
+}
+
+type LeaseRepository interface {
+	AcquireLease(name string, holder string, now time.Time, until time.Time) (bool, error)
+}
+
+// Store bundles one implementation of every repository so a backend can be
+// picked once at startup and handed around as a unit.
+type Store struct {
+	Students        StudentRepository
+	Teachers        TeacherRepository
+	Admins          AdminRepository
+	Courses         CourseRepository
+	Enrollments     EnrollmentRepository
+	Attendances     AttendanceRepository
+	ClassSessions   ClassSessionRepository
+	CheckInWindows  CheckInWindowRepository
+	CheckInAttempts CheckInAttemptRepository
+	Sessions        SessionRepository
+	Justifications  JustificationRepository
+	RiskAssessments RiskAssessmentRepository
+	Leases          LeaseRepository
+}
+
+func NewMongoStore(db database.Service) *Store {
+	return &Store{
+		Students:        &StudentRepo{MongoCollection: db.GetCollection("students")},
+		Teachers:        &TeacherRepo{MongoCollection: db.GetCollection("teachers")},
+		Admins:          &AdminRepo{MongoCollection: db.GetCollection("admins")},
+		Courses:         &CourseRepo{MongoCollection: db.GetCollection("courses")},
+		Enrollments:     &EnrollmentRepo{MongoCollection: db.GetCollection("enrollments")},
+		Attendances:     &AttendanceRepo{MongoCollection: db.GetCollection("attendances")},
+		ClassSessions:   &ClassSessionRepo{MongoCollection: db.GetCollection("class_sessions")},
+		CheckInWindows:  &CheckInWindowRepo{MongoCollection: db.GetCollection("checkin_windows")},
+		CheckInAttempts: &CheckInAttemptRepo{MongoCollection: db.GetCollection("checkin_attempts")},
+		Sessions:        &SessionRepo{MongoCollection: db.GetCollection("sessions")},
+		Justifications:  &JustificationRepo{MongoCollection: db.GetCollection("justifications")},
+		RiskAssessments: &RiskAssessmentRepo{MongoCollection: db.GetCollection("risk_assessments")},
+		Leases:          &LeaseRepo{MongoCollection: db.GetCollection("leases")},
+	}
+}
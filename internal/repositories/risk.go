@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RiskAssessmentRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *RiskAssessmentRepo) GetRiskAssessmentsByCourseID(CourseID string) ([]*types.RiskAssessment, error) {
+
+	courseID, err := ParseID(CourseID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CourseID: %w", err)
+	}
+
+	return r.find(bson.M{"course_id": courseID})
+}
+
+func (r *RiskAssessmentRepo) GetRiskAssessmentsByStudentID(StudentID string) ([]*types.RiskAssessment, error) {
+
+	studentID, err := ParseID(StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid StudentID: %w", err)
+	}
+
+	return r.find(bson.M{"student_id": studentID})
+}
+
+func (r *RiskAssessmentRepo) find(filter bson.M) ([]*types.RiskAssessment, error) {
+	var Assessments []*types.RiskAssessment
+
+	cursor, err := r.MongoCollection.Find(context.Background(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find RiskAssessments: %w", err)
+	}
+	if err := cursor.All(context.Background(), &Assessments); err != nil {
+		return nil, fmt.Errorf("failed to decode RiskAssessments: %w", err)
+	}
+
+	return Assessments, nil
+}
+
+// SaveRiskAssessment replaces the stored assessment of the same student in
+// the same course, or inserts it.
+func (r *RiskAssessmentRepo) SaveRiskAssessment(Assessment *types.RiskAssessment) error {
+	filter := bson.M{"course_id": Assessment.CourseID, "student_id": Assessment.StudentID}
+
+	doc := *Assessment
+	doc.ID = primitive.NilObjectID
+
+	_, err := r.MongoCollection.ReplaceOne(context.Background(), filter, &doc, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"money-minder/internal/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionRepo struct {
+	MongoCollection *mongo.Collection
+}
+
+func (r *SessionRepo) InsertSession(session *types.Session) (*InsertResult, error) {
+	result, err := r.MongoCollection.InsertOne(context.Background(), session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
+}
+
+func (r *SessionRepo) FindSessionByTokenHash(hash string) (*types.Session, error) {
+	var session types.Session
+	err := r.MongoCollection.FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// MarkSessionUsed flags a session as rotated. It only succeeds once, so two
+// concurrent refreshes with the same token cannot both win.
+func (r *SessionRepo) MarkSessionUsed(SessionID primitive.ObjectID, usedAt time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":        SessionID,
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"used_at": usedAt}}
+
+	result, err := r.MongoCollection.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *SessionRepo) IsFamilyActive(FamilyID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"family_id": FamilyID, "revoked_at": bson.M{"$exists": false}}
+
+	count, err := r.MongoCollection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *SessionRepo) RevokeFamily(FamilyID primitive.ObjectID) error {
+	return r.revoke(bson.M{"family_id": FamilyID, "revoked_at": bson.M{"$exists": false}})
+}
+
+func (r *SessionRepo) RevokeUserSessions(UserID primitive.ObjectID) error {
+	return r.revoke(bson.M{"user_id": UserID, "revoked_at": bson.M{"$exists": false}})
+}
+
+func (r *SessionRepo) revoke(filter bson.M) error {
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.MongoCollection.UpdateMany(context.Background(), filter, update)
+	return err
+}
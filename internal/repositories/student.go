@@ -14,32 +14,47 @@ type StudentRepo struct {
 	MongoCollection *mongo.Collection
 }
 
-func (r *StudentRepo) InsertStudent(usr *types.Student) (interface{}, error) {
+func (r *StudentRepo) InsertStudent(usr *types.Student) (*InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
 	result, err := r.MongoCollection.InsertOne((context.Background()), usr)
 	if err != nil {
-		return nil, err
+		return nil, AsConflict(err, ErrEmailTaken)
 	}
 
-	return result, nil
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
 }
 
-func (r *StudentRepo) DeleteStudent(usr *types.Student) (interface{}, error) {
+func (r *StudentRepo) DeleteStudent(usr *types.Student) (*DeleteResult, error) {
 	result, err := r.MongoCollection.DeleteOne((context.Background()), usr)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
+}
+
+func (r *StudentRepo) DeleteStudentByID(usrID string) (*DeleteResult, error) {
+	id, err := ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.MongoCollection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
 }
 
 func (r *StudentRepo) FindStudentByID(usrID string) (*types.Student, error) {
-	id, err := primitive.ObjectIDFromHex(usrID)
+	id, err := ParseID(usrID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.D{{"_id", id}}
+	filter := bson.M{"_id": id}
 
 	var usr types.Student
 
@@ -57,6 +72,7 @@ func (r *StudentRepo) FindStudentByID(usrID string) (*types.Student, error) {
 }
 
 func (r *StudentRepo) FindStudentByEmail(email string) (*types.Student, error) {
+	email = types.NormalizeEmail(email)
 	var student types.Student
 	err := r.MongoCollection.FindOne(context.Background(), bson.M{"email": email}).Decode(&student)
 	if err != nil {
@@ -84,61 +100,37 @@ func (r *StudentRepo) FindAllStudents() ([]types.Student, error) {
 	return usrs, nil
 }
 
-func (r *StudentRepo) AddCourse(StudentID string, CourseID string, CourseRepo *CourseRepo) error {
-
-	StudentObjectID, err := primitive.ObjectIDFromHex(StudentID)
-	if err != nil {
-		return fmt.Errorf("invalid Student ID: %w", err)
+func (r *StudentRepo) FindStudentsByIDs(ids []primitive.ObjectID) ([]*types.Student, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
 
-	Course, err := CourseRepo.FindCourseByID(CourseID)
+	var Students []*types.Student
+	cursor, err := r.MongoCollection.Find(context.Background(), bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return fmt.Errorf("failed to find Course: %w", err)
-	}
-	if Course == nil {
-		return fmt.Errorf("Course not found")
+		return nil, fmt.Errorf("failed to find Students: %w", err)
 	}
-
-	filter := bson.D{{"_id", StudentObjectID}}
-	update := bson.D{{"$push", bson.D{{"courses", Course}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to add Course to Student: %w", err)
+	if err := cursor.All(context.Background(), &Students); err != nil {
+		return nil, fmt.Errorf("failed to decode Students: %w", err)
 	}
 
-	return nil
+	return Students, nil
 }
 
-func (r *StudentRepo) RemoveCourse(StudentID string, CourseID string, CourseRepo *CourseRepo) error {
-
-	StudentObjectID, err := primitive.ObjectIDFromHex(StudentID)
-	if err != nil {
-		return fmt.Errorf("invalid Student ID: %w", err)
+func (r *StudentRepo) ListStudents(ids []primitive.ObjectID, q Query) (*Page[*types.Student], error) {
+	var byID bson.M
+	if ids != nil {
+		byID = bson.M{"_id": bson.M{"$in": ids}}
 	}
 
-	Course, err := CourseRepo.FindCourseByID(CourseID)
-	if err != nil {
-		return fmt.Errorf("failed to find Course: %w", err)
-	}
-	if Course == nil {
-		return fmt.Errorf("Course not found")
-	}
-
-	filter := bson.D{{"_id", StudentObjectID}}
-	update := bson.D{{"$pull", bson.D{{"courses", bson.D{{"$eq", Course}}}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to delete Course from Student: %w", err)
-	}
-
-	return nil
+	return findPage(r.MongoCollection, q, StudentSorts, studentID, byID, q.SearchFilter("name", "email"), q.AccountFilter())
 }
 
-func (r *StudentRepo) AddAttendance(StudentID string, AttendanceID string, AttendanceRepo *AttendanceRepo) error {
+func studentID(s *types.Student) primitive.ObjectID { return s.ID }
+
+func (r *StudentRepo) AddAttendance(StudentID string, AttendanceID string, AttendanceRepo AttendanceRepository) error {
 
-	StudentObjID, err := primitive.ObjectIDFromHex(StudentID)
+	StudentObjID, err := ParseID(StudentID)
 	if err != nil {
 		return fmt.Errorf("invalid Student ID: %w", err)
 	}
@@ -151,8 +143,8 @@ func (r *StudentRepo) AddAttendance(StudentID string, AttendanceID string, Atten
 		return fmt.Errorf("Attendance not found")
 	}
 
-	filter := bson.D{{"_id", StudentObjID}}
-	update := bson.D{{"$push", bson.D{{"attendances", Attendance}}}}
+	filter := bson.M{"_id": StudentObjID}
+	update := bson.M{"$push": bson.M{"attendances": Attendance}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
@@ -162,9 +154,9 @@ func (r *StudentRepo) AddAttendance(StudentID string, AttendanceID string, Atten
 	return nil
 }
 
-func (r *StudentRepo) RemoveAttendance(StudentID string, AttendanceID string, AttendanceRepo *AttendanceRepo) error {
+func (r *StudentRepo) RemoveAttendance(StudentID string, AttendanceID string, AttendanceRepo AttendanceRepository) error {
 
-	StudentObjectID, err := primitive.ObjectIDFromHex(StudentID)
+	StudentObjectID, err := ParseID(StudentID)
 	if err != nil {
 		return fmt.Errorf("invalid Student ID: %w", err)
 	}
@@ -177,8 +169,8 @@ func (r *StudentRepo) RemoveAttendance(StudentID string, AttendanceID string, At
 		return fmt.Errorf("Attendance not found")
 	}
 
-	filter := bson.D{{"_id", StudentObjectID}}
-	update := bson.D{{"$pull", bson.D{{"attendances", bson.D{{"$eq", Attendance}}}}}}
+	filter := bson.M{"_id": StudentObjectID}
+	update := bson.M{"$pull": bson.M{"attendances": bson.M{"$eq": Attendance}}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
@@ -188,33 +180,10 @@ func (r *StudentRepo) RemoveAttendance(StudentID string, AttendanceID string, At
 	return nil
 }
 
-func (r *StudentRepo) GetStudentsByCourseID(CourseID string) ([]*types.Student, error) {
-
-	courseID, err := primitive.ObjectIDFromHex(CourseID)
-	if err != nil {
-		return nil, fmt.Errorf("invalid courseID: %w", err)
-	}
-
-	filter := bson.M{"courses._id": courseID}
-	var Students []*types.Student
-
-	cursor, err := r.MongoCollection.Find(context.Background(), filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find Students: %w", err)
-	}
-	defer cursor.Close(context.Background())
-
-	for cursor.Next(context.Background()) {
-		var Student types.Student
-		if err := cursor.Decode(&Student); err != nil {
-			return nil, fmt.Errorf("failed to decode Attendance: %w", err)
-		}
-		Students = append(Students, &Student)
-	}
-
-	if err := cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
-	}
+func (r *StudentRepo) SetStatus(usrID string, status string) error {
+	return setAccountField(r.MongoCollection, usrID, "status", status)
+}
 
-	return Students, nil
+func (r *StudentRepo) UpdatePassword(usrID string, hash string) error {
+	return setAccountField(r.MongoCollection, usrID, "password", hash)
 }
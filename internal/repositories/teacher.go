@@ -14,31 +14,46 @@ type TeacherRepo struct {
 	MongoCollection *mongo.Collection
 }
 
-func (r *TeacherRepo) InsertTeacher(usr *types.Teacher) (interface{}, error) {
+func (r *TeacherRepo) InsertTeacher(usr *types.Teacher) (*InsertResult, error) {
+	usr.Email = types.NormalizeEmail(usr.Email)
 	result, err := r.MongoCollection.InsertOne((context.Background()), usr)
 	if err != nil {
-		return nil, err
+		return nil, AsConflict(err, ErrEmailTaken)
 	}
 
-	return result, nil
+	return &InsertResult{InsertedID: result.InsertedID.(primitive.ObjectID)}, nil
 }
 
-func (r *TeacherRepo) DeleteTeacher(usr *types.Teacher) (interface{}, error) {
+func (r *TeacherRepo) DeleteTeacher(usr *types.Teacher) (*DeleteResult, error) {
 	result, err := r.MongoCollection.DeleteOne((context.Background()), usr)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
+}
+
+func (r *TeacherRepo) DeleteTeacherByID(usrID string) (*DeleteResult, error) {
+	id, err := ParseID(usrID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.MongoCollection.DeleteOne(context.Background(), bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteResult{DeletedCount: result.DeletedCount}, nil
 }
 
 func (r *TeacherRepo) FindTeacherByID(usrID string) (*types.Teacher, error) {
-	id, err := primitive.ObjectIDFromHex(usrID)
+	id, err := ParseID(usrID)
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.D{{"_id", id}}
+	filter := bson.M{"_id": id}
 
 	var usr types.Teacher
 
@@ -56,6 +71,7 @@ func (r *TeacherRepo) FindTeacherByID(usrID string) (*types.Teacher, error) {
 }
 
 func (r *TeacherRepo) FindTeacherByEmail(email string) (*types.Teacher, error) {
+	email = types.NormalizeEmail(email)
 	var teacher types.Teacher
 	err := r.MongoCollection.FindOne(context.Background(), bson.M{"email": email}).Decode(&teacher)
 	if err != nil {
@@ -83,14 +99,20 @@ func (r *TeacherRepo) FindAllTeachers() ([]types.Teacher, error) {
 	return usrs, nil
 }
 
+func (r *TeacherRepo) ListTeachers(q Query) (*Page[*types.Teacher], error) {
+	return findPage(r.MongoCollection, q, TeacherSorts, teacherID, q.SearchFilter("name", "email"), q.AccountFilter())
+}
+
+func teacherID(t *types.Teacher) primitive.ObjectID { return t.ID }
+
 func (r *TeacherRepo) UpdateName(usrID string, newName string) error {
-	id, err := primitive.ObjectIDFromHex(usrID)
+	id, err := ParseID(usrID)
 	if err != nil {
 		return err
 	}
 
-	filter := bson.D{{"_id", id}}
-	update := bson.D{{"$set", bson.D{{"name", newName}}}}
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"name": newName}}
 
 	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
@@ -100,54 +122,10 @@ func (r *TeacherRepo) UpdateName(usrID string, newName string) error {
 	return nil
 }
 
-func (r *TeacherRepo) AddCourse(TeacherID string, CourseID string, CourseRepo *CourseRepo) error {
-
-	TeacherObjectID, err := primitive.ObjectIDFromHex(TeacherID)
-	if err != nil {
-		return fmt.Errorf("invalid Teacher ID: %w", err)
-	}
-
-	Course, err := CourseRepo.FindCourseByID(CourseID)
-	if err != nil {
-		return fmt.Errorf("failed to find Course: %w", err)
-	}
-	if Course == nil {
-		return fmt.Errorf("Course not found")
-	}
-
-	filter := bson.D{{"_id", TeacherObjectID}}
-	update := bson.D{{"$push", bson.D{{"courses", Course}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to add Course to Teacher: %w", err)
-	}
-
-	return nil
+func (r *TeacherRepo) SetStatus(usrID string, status string) error {
+	return setAccountField(r.MongoCollection, usrID, "status", status)
 }
 
-func (r *TeacherRepo) RemoveCourse(TeacherID string, CourseID string, CourseRepo *CourseRepo) error {
-
-	TeacherObjectID, err := primitive.ObjectIDFromHex(TeacherID)
-	if err != nil {
-		return fmt.Errorf("invalid Teacher ID: %w", err)
-	}
-
-	Course, err := CourseRepo.FindCourseByID(CourseID)
-	if err != nil {
-		return fmt.Errorf("failed to find Course: %w", err)
-	}
-	if Course == nil {
-		return fmt.Errorf("Course not found")
-	}
-
-	filter := bson.D{{"_id", TeacherObjectID}}
-	update := bson.D{{"$pull", bson.D{{"courses", bson.D{{"$eq", Course}}}}}}
-
-	_, err = r.MongoCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to delete Course from Teacher: %w", err)
-	}
-
-	return nil
+func (r *TeacherRepo) UpdatePassword(usrID string, hash string) error {
+	return setAccountField(r.MongoCollection, usrID, "password", hash)
 }
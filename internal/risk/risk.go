@@ -0,0 +1,113 @@
+// Package risk flags students whose attendance puts them in danger of
+// failing a course.
+package risk
+
+import (
+	"fmt"
+	"money-minder/internal/types"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Evaluate assesses every student enrolled in Course, given as Students,
+// from the course's attendance records. previous holds the stored assessments, whose Since is
+// carried over while a student stays out of the ok level.
+func Evaluate(Course *types.Course, Students []*types.Student, Attendances []*types.Attendance, previous []*types.RiskAssessment, now time.Time) []types.RiskAssessment {
+	byStudent := make(map[primitive.ObjectID][]*types.Attendance)
+	for _, a := range Attendances {
+		byStudent[a.StudentID] = append(byStudent[a.StudentID], a)
+	}
+
+	last := make(map[primitive.ObjectID]*types.RiskAssessment, len(previous))
+	for _, p := range previous {
+		last[p.StudentID] = p
+	}
+
+	assessments := []types.RiskAssessment{}
+	for _, Student := range Students {
+		assessment := Assess(Course, *Student, byStudent[Student.ID], last[Student.ID], now)
+		assessments = append(assessments, assessment)
+	}
+	return assessments
+}
+
+// Assess rates one student's attendance records in Course against the
+// course policy.
+func Assess(Course *types.Course, Student types.Student, Attendances []*types.Attendance, previous *types.RiskAssessment, now time.Time) types.RiskAssessment {
+	policy := Course.AttendancePolicy()
+
+	assessment := types.RiskAssessment{
+		CourseID:    Course.ID,
+		StudentID:   Student.ID,
+		Name:        Student.Name,
+		Level:       types.RiskOK,
+		EvaluatedAt: now,
+	}
+
+	summary := types.AttendanceSummary{StudentID: Student.ID}
+	if counts := types.SummarizeAttendances(Attendances); len(counts) > 0 {
+		summary = counts[0]
+	}
+	summary.ApplyPolicy(policy)
+
+	assessment.EffectiveAbsences = summary.EffectiveAbsences
+	assessment.AttendanceRate = summary.EffectiveAttendanceRate()
+	assessment.ConsecutiveAbsences = consecutiveAbsences(Attendances)
+
+	raise := func(level, reason string) {
+		if level == types.RiskAtRisk || assessment.Level == types.RiskOK {
+			assessment.Level = level
+		}
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	if rate := assessment.AttendanceRate; rate != nil && policy.MinAttendancePercent > 0 {
+		switch {
+		case *rate < policy.MinAttendancePercent:
+			raise(types.RiskAtRisk, fmt.Sprintf("attendance of %.1f%% is below the required %.0f%%", *rate, policy.MinAttendancePercent))
+		case *rate < policy.MinAttendancePercent+policy.WarningMarginPercent:
+			raise(types.RiskWarning, fmt.Sprintf("attendance of %.1f%% is close to the required %.0f%%", *rate, policy.MinAttendancePercent))
+		}
+	}
+
+	if limit := policy.MaxConsecutiveAbsences; limit > 0 {
+		switch {
+		case assessment.ConsecutiveAbsences >= limit:
+			raise(types.RiskAtRisk, fmt.Sprintf("%d absences in a row, the limit is %d", assessment.ConsecutiveAbsences, limit))
+		case limit > 1 && assessment.ConsecutiveAbsences == limit-1:
+			raise(types.RiskWarning, fmt.Sprintf("%d absences in a row, the limit is %d", assessment.ConsecutiveAbsences, limit))
+		}
+	}
+
+	if assessment.Level != types.RiskOK {
+		since := now
+		if previous != nil && previous.Level != types.RiskOK && previous.Since != nil {
+			since = *previous.Since
+		}
+		assessment.Since = &since
+	}
+
+	return assessment
+}
+
+// consecutiveAbsences counts the absences at the end of the student's
+// record. Excused classes neither count nor break the streak.
+func consecutiveAbsences(Attendances []*types.Attendance) int {
+	sorted := append([]*types.Attendance(nil), Attendances...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	streak := 0
+	for i := len(sorted) - 1; i >= 0; i-- {
+		switch sorted[i].Status {
+		case types.StatusAbsent:
+			streak++
+		case types.StatusExcused:
+			continue
+		default:
+			return streak
+		}
+	}
+	return streak
+}
@@ -0,0 +1,184 @@
+// Package schedule validates recurring course schedules and expands them
+// into concrete class occurrences.
+package schedule
+
+import (
+	"fmt"
+	"money-minder/internal/types"
+	"sort"
+	"strings"
+	"time"
+
+	// Windows machines have no system zoneinfo; embed it so timezones load everywhere.
+	_ "time/tzdata"
+)
+
+const (
+	DateLayout = "2006-01-02"
+	TimeLayout = "15:04"
+
+	// MaxRange bounds how many days a single expansion may cover.
+	MaxRange = 366 * 24 * time.Hour
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Validate checks every slot and normalizes weekday names to lower case.
+func Validate(slots []types.ScheduleSlot) error {
+	for i := range slots {
+		if err := validateSlot(&slots[i]); err != nil {
+			return fmt.Errorf("schedules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateSlot(slot *types.ScheduleSlot) error {
+	slot.Weekday = strings.ToLower(strings.TrimSpace(slot.Weekday))
+	if _, ok := weekdays[slot.Weekday]; !ok {
+		return fmt.Errorf("weekday must be one of monday..sunday, got %q", slot.Weekday)
+	}
+
+	start, err := time.Parse(TimeLayout, slot.StartTime)
+	if err != nil {
+		return fmt.Errorf("startTime must be HH:MM")
+	}
+	end, err := time.Parse(TimeLayout, slot.EndTime)
+	if err != nil {
+		return fmt.Errorf("endTime must be HH:MM")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("endTime must be after startTime")
+	}
+
+	if slot.Timezone == "" {
+		return fmt.Errorf("timezone is required, e.g. America/Santiago")
+	}
+	if _, err := time.LoadLocation(slot.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", slot.Timezone)
+	}
+
+	var from, until time.Time
+	if slot.ValidFrom != "" {
+		if from, err = time.Parse(DateLayout, slot.ValidFrom); err != nil {
+			return fmt.Errorf("validFrom must be YYYY-MM-DD")
+		}
+	}
+	if slot.ValidUntil != "" {
+		if until, err = time.Parse(DateLayout, slot.ValidUntil); err != nil {
+			return fmt.Errorf("validUntil must be YYYY-MM-DD")
+		}
+	}
+	if !from.IsZero() && !until.IsZero() && until.Before(from) {
+		return fmt.Errorf("validUntil must not be before validFrom")
+	}
+
+	for _, exception := range slot.Exceptions {
+		if _, err := time.Parse(DateLayout, exception); err != nil {
+			return fmt.Errorf("exception %q must be YYYY-MM-DD", exception)
+		}
+	}
+
+	return nil
+}
+
+// Expand returns every occurrence of slots whose local date, in the slot's
+// own timezone, falls between from and to inclusive. Slots are expected to
+// have passed Validate; invalid ones are skipped. Occurrences are sorted by
+// start time.
+func Expand(slots []types.ScheduleSlot, from, to time.Time) []types.Occurrence {
+	occurrences := []types.Occurrence{}
+
+	for i, slot := range slots {
+		weekday, ok := weekdays[strings.ToLower(slot.Weekday)]
+		if !ok {
+			continue
+		}
+		loc, err := time.LoadLocation(slot.Timezone)
+		if err != nil {
+			continue
+		}
+		start, err := time.Parse(TimeLayout, slot.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(TimeLayout, slot.EndTime)
+		if err != nil {
+			continue
+		}
+
+		skip := make(map[string]bool, len(slot.Exceptions))
+		for _, exception := range slot.Exceptions {
+			skip[exception] = true
+		}
+
+		for day := dateOf(from); !day.After(dateOf(to)); day = day.AddDate(0, 0, 1) {
+			date := day.Format(DateLayout)
+			if day.Weekday() != weekday || skip[date] {
+				continue
+			}
+			if slot.ValidFrom != "" && date < slot.ValidFrom {
+				continue
+			}
+			if slot.ValidUntil != "" && date > slot.ValidUntil {
+				continue
+			}
+
+			occurrences = append(occurrences, types.Occurrence{
+				Slot:  i,
+				Start: at(day, start, loc),
+				End:   at(day, end, loc),
+				Room:  slot.Room,
+			})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(a, b int) bool {
+		return occurrences[a].Start.Before(occurrences[b].Start)
+	})
+	return occurrences
+}
+
+// Overlapping returns the first occurrence of slots that overlaps the
+// interval from..to, if any.
+func Overlapping(slots []types.ScheduleSlot, from, to time.Time) (types.Occurrence, bool) {
+	// A day on each side covers slots whose timezone puts them on another
+	// calendar date than from and to.
+	for _, occurrence := range Expand(slots, from.AddDate(0, 0, -1), to.AddDate(0, 0, 1)) {
+		if occurrence.Start.Before(to) && occurrence.End.After(from) {
+			return occurrence, true
+		}
+	}
+	return types.Occurrence{}, false
+}
+
+// dateOf drops the clock and location of t, keeping its calendar date.
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// at combines a calendar day with a wall-clock time in loc.
+func at(day, clock time.Time, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, loc)
+}
+
+// Location returns the timezone of the first slot, which is where a course
+// meets, or UTC for courses without a schedule.
+func Location(slots []types.ScheduleSlot) *time.Location {
+	for _, slot := range slots {
+		if loc, err := time.LoadLocation(slot.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
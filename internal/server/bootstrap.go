@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bootstrapAdmin creates the first admin from ADMIN_EMAIL, ADMIN_PASSWORD
+// and the optional ADMIN_NAME when the store has no admin yet. Once one
+// exists the variables are ignored, so they can be dropped after the first
+// start.
+func bootstrapAdmin(store *repositories.Store) error {
+	email, password := os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_PASSWORD")
+	if email == "" && password == "" {
+		return nil
+	}
+	if email == "" || password == "" {
+		return fmt.Errorf("bootstrap admin: ADMIN_EMAIL and ADMIN_PASSWORD must be set together")
+	}
+	if len(password) < 8 || len(password) > 72 {
+		return fmt.Errorf("bootstrap admin: ADMIN_PASSWORD must have between 8 and 72 characters")
+	}
+
+	count, err := store.Admins.CountAdmins()
+	if err != nil {
+		return fmt.Errorf("bootstrap admin: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("bootstrap admin: %w", err)
+	}
+
+	name := os.Getenv("ADMIN_NAME")
+	if name == "" {
+		name = "Admin"
+	}
+
+	admin := &types.Admin{Name: name, Email: email, Password: string(hashedPassword), Status: types.AccountActive}
+	if _, err := store.Admins.InsertAdmin(admin); err != nil {
+		return fmt.Errorf("bootstrap admin: %w", err)
+	}
+
+	slog.Info("Created the first admin", "email", email)
+	return nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"money-minder/internal/handlers"
+	"money-minder/internal/repositories"
+	"net/http"
+	"strings"
+)
+
+// Machine-readable codes of the error envelope. Errors that carry no code
+// of their own get one derived from their status, e.g. "bad_request".
+const (
+	codeInvalidID        = "invalid_id"
+	codeInvalidCursor    = "invalid_cursor"
+	codeValidationFailed = "validation_failed"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeForbidden        = "forbidden"
+	codeTooLarge         = "payload_too_large"
+	codeInternal         = "internal"
+)
+
+// errorResponse is the body of every error the API answers with.
+type errorResponse struct {
+	Status    int                       `json:"status"`
+	Code      string                    `json:"code"`
+	Message   string                    `json:"message"`
+	Fields    []repositories.FieldError `json:"fields,omitempty"`
+	RequestID string                    `json:"requestId,omitempty"`
+}
+
+// writeError answers the request with the envelope for err. Errors the API
+// does not know about are logged and reported as a generic internal error
+// so storage details never reach the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := errorResponse{RequestID: requestID(r.Context())}
+
+	var (
+		apiErr     handlers.APIError
+		validation *repositories.ValidationError
+		conflict   *repositories.ConflictError
+		notFound   *repositories.NotFoundError
+		tooLarge   *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &apiErr):
+		resp.Status, resp.Code, resp.Message = apiErr.Status, statusCode(apiErr.Status), apiErr.Msg
+	case errors.As(err, &validation):
+		resp.Status, resp.Code, resp.Message = http.StatusBadRequest, codeValidationFailed, "The request has invalid fields"
+		resp.Fields = validation.Fields
+	case errors.As(err, &conflict):
+		resp.Status, resp.Code, resp.Message = http.StatusConflict, codeConflict, conflict.Msg
+	case errors.As(err, &notFound):
+		resp.Status, resp.Code, resp.Message = http.StatusNotFound, codeNotFound, notFound.Error()
+	case errors.Is(err, repositories.ErrNotFound):
+		resp.Status, resp.Code, resp.Message = http.StatusNotFound, codeNotFound, "Not found"
+	case errors.Is(err, repositories.ErrInvalidID):
+		resp.Status, resp.Code, resp.Message = http.StatusBadRequest, codeInvalidID, "Malformed ID"
+	case errors.Is(err, repositories.ErrInvalidCursor):
+		resp.Status, resp.Code, resp.Message = http.StatusBadRequest, codeInvalidCursor, "The cursor does not belong to this list or sort"
+	case errors.Is(err, repositories.ErrForbidden):
+		resp.Status, resp.Code, resp.Message = http.StatusForbidden, codeForbidden, "You are not allowed to perform this action"
+	case errors.As(err, &tooLarge):
+		resp.Status, resp.Code, resp.Message = http.StatusRequestEntityTooLarge, codeTooLarge, "The request body is too large"
+	default:
+		resp.Status, resp.Code, resp.Message = http.StatusInternalServerError, codeInternal, "Internal server error"
+	}
+
+	if resp.Status >= http.StatusInternalServerError {
+		slog.Error("API Error", "err", err, "status", resp.Status, "method", r.Method, "path", r.URL.Path, "request_id", resp.RequestID)
+	} else {
+		slog.Info("API Error", "err", err, "status", resp.Status, "method", r.Method, "path", r.URL.Path, "request_id", resp.RequestID)
+	}
+
+	handlers.WriteJSON(w, resp.Status, resp)
+}
+
+// statusCode derives an error code from an HTTP status.
+func statusCode(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return codeInternal
+	case http.StatusRequestEntityTooLarge:
+		return codeTooLarge
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+type requestIDKey struct{}
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDMiddleware tags every request with an ID, reusing the one sent
+// by the client or a proxy when it looks sane, and echoes it in the
+// response so a failed call can be matched with the server logs.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"money-minder/internal/auth"
+	"money-minder/internal/handlers"
+	"money-minder/internal/repositories"
+	"money-minder/internal/types"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rule reports whether the caller identified by claims may perform r.
+type rule func(r *http.Request, claims *auth.Claims) (bool, error)
+
+// authorize requires a valid token and lets the request through when at
+// least one of the route's rules accepts the caller.
+func (s *Server) authorize(next http.HandlerFunc, rules ...rule) http.HandlerFunc {
+	return s.jwtMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.GetClaims(r.Context())
+		if !ok {
+			writeError(w, r, handlers.APIError{Status: http.StatusUnauthorized, Msg: "Missing credentials"})
+			return
+		}
+
+		for _, allowed := range rules {
+			ok, err := allowed(r, claims)
+			if err != nil {
+				writeError(w, r, fmt.Errorf("authorizing request: %w", err))
+				return
+			}
+			if ok {
+				next(w, r)
+				return
+			}
+		}
+
+		writeError(w, r, repositories.ErrForbidden)
+	})
+}
+
+var (
+	isAdmin   = hasRole(auth.RoleAdmin)
+	isTeacher = hasRole(auth.RoleTeacher)
+	isStudent = hasRole(auth.RoleStudent)
+)
+
+func hasRole(role string) rule {
+	return func(r *http.Request, claims *auth.Claims) (bool, error) {
+		return claims.Role == role, nil
+	}
+}
+
+// isSelf accepts a caller with the given role whose ID is the {id} path value.
+func isSelf(role string) rule {
+	return func(r *http.Request, claims *auth.Claims) (bool, error) {
+		return claims.Role == role && claims.ID.Hex() == r.PathValue("id"), nil
+	}
+}
+
+// allOf accepts a caller only when every rule does.
+func allOf(rules ...rule) rule {
+	return func(r *http.Request, claims *auth.Claims) (bool, error) {
+		for _, required := range rules {
+			ok, err := required(r, claims)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// courseIDFunc extracts the course a request acts on.
+type courseIDFunc func(r *http.Request) (string, error)
+
+// teachesCourse accepts a teacher listed as Course.Teacher for the course
+// returned by courseID.
+func (s *Server) teachesCourse(courseID courseIDFunc) rule {
+	return func(r *http.Request, claims *auth.Claims) (bool, error) {
+		if claims.Role != auth.RoleTeacher {
+			return false, nil
+		}
+
+		id, err := courseID(r)
+		if err != nil {
+			return false, err
+		}
+		if id == "" {
+			return false, nil
+		}
+
+		course, err := s.store.Courses.FindCourseByID(id)
+		if err != nil {
+			return false, err
+		}
+		if course == nil {
+			return false, nil
+		}
+
+		return course.Teacher == claims.ID, nil
+	}
+}
+
+// teachesStudent accepts a teacher who owns a course the student in the
+// {id} path value is enrolled in.
+func (s *Server) teachesStudent(r *http.Request, claims *auth.Claims) (bool, error) {
+	if claims.Role != auth.RoleTeacher {
+		return false, nil
+	}
+
+	Enrollments, err := s.store.Enrollments.GetEnrollmentsByUserID(r.PathValue("id"), types.EnrollmentRoleStudent)
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(Enrollments))
+	for _, enrollment := range Enrollments {
+		ids = append(ids, enrollment.CourseID)
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	Courses, err := s.store.Courses.FindCoursesByIDs(ids)
+	if err != nil {
+		return false, err
+	}
+	for _, course := range Courses {
+		if course.Teacher == claims.ID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func coursePathID(r *http.Request) (string, error) {
+	return r.PathValue("id"), nil
+}
+
+// courseBodyID reads the courseId field of a JSON body, falling back to the
+// course of its sessionId, and restores the body for the handler.
+func (s *Server) courseBodyID(r *http.Request) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, handlers.MaxJSONBody))
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		CourseID  string `json:"courseId"`
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", nil
+	}
+
+	if req.CourseID == "" && req.SessionID != "" {
+		ClassSession, err := s.store.ClassSessions.FindClassSessionByID(req.SessionID)
+		if err != nil || ClassSession == nil {
+			return "", nil
+		}
+		return ClassSession.CourseID.Hex(), nil
+	}
+
+	return req.CourseID, nil
+}
+
+// attendanceCourseID resolves the course of the attendance in the {id} path value.
+func (s *Server) attendanceCourseID(r *http.Request) (string, error) {
+	attendance, err := s.store.Attendances.FindAttendanceByID(r.PathValue("id"))
+	if err != nil || attendance == nil {
+		return "", nil
+	}
+
+	return attendance.CourseID.Hex(), nil
+}
+
+// classSessionCourseID resolves the course of the class session in the {id} path value.
+func (s *Server) classSessionCourseID(r *http.Request) (string, error) {
+	ClassSession, err := s.store.ClassSessions.FindClassSessionByID(r.PathValue("id"))
+	if err != nil || ClassSession == nil {
+		return "", nil
+	}
+
+	return ClassSession.CourseID.Hex(), nil
+}
+
+// checkInCourseID resolves the course of the check-in window in the {id} path value.
+func (s *Server) checkInCourseID(r *http.Request) (string, error) {
+	Window, err := s.store.CheckInWindows.FindCheckInWindowByID(r.PathValue("id"))
+	if err != nil || Window == nil {
+		return "", nil
+	}
+
+	return Window.CourseID.Hex(), nil
+}
+
+// justificationCourseID resolves the course of the justification in the {id} path value.
+func (s *Server) justificationCourseID(r *http.Request) (string, error) {
+	Justification, err := s.store.Justifications.FindJustificationByID(r.PathValue("id"))
+	if err != nil || Justification == nil {
+		return "", nil
+	}
+
+	return Justification.CourseID.Hex(), nil
+}
+
+// submittedJustification accepts the student who submitted the
+// justification in the {id} path value.
+func (s *Server) submittedJustification(r *http.Request, claims *auth.Claims) (bool, error) {
+	if claims.Role != auth.RoleStudent {
+		return false, nil
+	}
+
+	Justification, err := s.store.Justifications.FindJustificationByID(r.PathValue("id"))
+	if err != nil || Justification == nil {
+		return false, nil
+	}
+
+	return Justification.StudentID == claims.ID, nil
+}
+
+// ownAttendance accepts the student the attendance in the {id} path value
+// belongs to.
+func (s *Server) ownAttendance(r *http.Request, claims *auth.Claims) (bool, error) {
+	if claims.Role != auth.RoleStudent {
+		return false, nil
+	}
+
+	Attendance, err := s.store.Attendances.FindAttendanceByID(r.PathValue("id"))
+	if err != nil {
+		return false, err
+	}
+
+	return Attendance != nil && Attendance.StudentID == claims.ID, nil
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"money-minder/internal/auth"
+	"money-minder/internal/repositories"
+	"money-minder/internal/repositories/memory"
+	"money-minder/internal/storage"
+	"money-minder/internal/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// policyFixture is a course owned by one teacher, with one enrolled student
+// who has an attendance, plus a second teacher and a second student who
+// have nothing to do with it.
+type policyFixture struct {
+	handler http.Handler
+	tokens  map[string]string
+
+	course, attendance     string
+	student, otherStudent  string
+	owner, nonOwner, admin string
+}
+
+func newPolicyFixture(t *testing.T) *policyFixture {
+	t.Helper()
+
+	store := memory.NewStore()
+	tokens, err := auth.NewTokenService(auth.TokenConfig{Secret: []byte("test-secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &policyFixture{
+		handler: New(0, nil, store, tokens, files).Handler,
+		tokens:  map[string]string{},
+	}
+
+	insert := func(res *repositories.InsertResult, err error) primitive.ObjectID {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.InsertedID
+	}
+	login := func(name string, id primitive.ObjectID, role string) string {
+		t.Helper()
+		family := primitive.NewObjectID()
+		if _, err := store.Sessions.InsertSession(&types.Session{FamilyID: family, UserID: id, Role: role, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := tokens.Issue(id, name+"@x.com", role, family)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.tokens[name] = token
+		return id.Hex()
+	}
+
+	student := insert(store.Students.InsertStudent(&types.Student{Name: "Student", Email: "student@x.com"}))
+	otherStudent := insert(store.Students.InsertStudent(&types.Student{Name: "Other", Email: "other@x.com"}))
+	owner := insert(store.Teachers.InsertTeacher(&types.Teacher{Name: "Owner", Email: "owner@x.com"}))
+	nonOwner := insert(store.Teachers.InsertTeacher(&types.Teacher{Name: "Non-owner", Email: "nonowner@x.com"}))
+	admin := insert(store.Admins.InsertAdmin(&types.Admin{Name: "Admin", Email: "admin@x.com"}))
+
+	course := insert(store.Courses.InsertCourse(&types.Course{Name: "Math", Teacher: owner}))
+	if _, err := store.Enrollments.Enroll(course, student, types.EnrollmentRoleStudent, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	attendance := insert(store.Attendances.InsertAttendance(&types.Attendance{
+		CourseID: course, StudentID: student, Type: "class", Date: time.Now(), Status: types.StatusPresent, Present: true,
+	}))
+
+	f.student = login("student", student, auth.RoleStudent)
+	f.otherStudent = login("otherStudent", otherStudent, auth.RoleStudent)
+	f.owner = login("owner", owner, auth.RoleTeacher)
+	f.nonOwner = login("nonOwner", nonOwner, auth.RoleTeacher)
+	f.admin = login("admin", admin, auth.RoleAdmin)
+	f.course = course.Hex()
+	f.attendance = attendance.Hex()
+	return f
+}
+
+func (f *policyFixture) do(caller, method, path, body string) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token, ok := f.tokens[caller]; ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRoutePolicies(t *testing.T) {
+	f := newPolicyFixture(t)
+	const (
+		ok        = http.StatusOK
+		forbidden = http.StatusForbidden
+	)
+
+	tests := []struct {
+		method, path, body string
+		// want is the status each caller gets, by fixture token name.
+		want map[string]int
+	}{
+		{
+			method: "GET", path: "/students/" + f.student,
+			want: map[string]int{"student": ok, "otherStudent": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/students/" + f.student + "/courses",
+			want: map[string]int{"student": ok, "otherStudent": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/students/" + f.student + "/attendances",
+			want: map[string]int{"student": ok, "otherStudent": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/attendance/student/" + f.student,
+			want: map[string]int{"student": ok, "otherStudent": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			// The other student takes none of the owner's courses.
+			method: "GET", path: "/attendance/student/" + f.otherStudent,
+			want: map[string]int{"otherStudent": ok, "owner": forbidden, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/attendance/" + f.attendance,
+			want: map[string]int{"student": ok, "otherStudent": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/attendance/course/" + f.course,
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/courses/" + f.course + "/stats",
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/courses/" + f.course + "/students",
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "PATCH", path: "/courses/" + f.course + "/policy", body: `{"lateAfterMinutes": 10}`,
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			// A teacher cannot attach themselves to a course they do not own.
+			method: "PATCH", path: "/teachers/" + f.nonOwner + "/courses", body: `{"CourseId": "` + f.course + `"}`,
+			want: map[string]int{"student": forbidden, "owner": forbidden, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "PATCH", path: "/teachers/" + f.owner + "/courses", body: `{"CourseId": "` + f.course + `"}`,
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "POST", path: "/courses", body: `{"name": "Physics", "teacher": "` + f.owner + `"}`,
+			want: map[string]int{"student": forbidden, "owner": ok, "nonOwner": forbidden, "admin": ok},
+		},
+		{
+			method: "GET", path: "/admin/users?role=student",
+			want: map[string]int{"student": forbidden, "owner": forbidden, "admin": ok},
+		},
+		{
+			// Ownership rules look the course up, so a malformed id is a
+			// bad request rather than a refusal.
+			method: "GET", path: "/courses/not-an-id/stats",
+			want: map[string]int{"owner": http.StatusBadRequest, "admin": http.StatusBadRequest},
+		},
+		{
+			method: "GET", path: "/courses/" + f.course + "/stats",
+			want: map[string]int{"anonymous": http.StatusUnauthorized},
+		},
+	}
+
+	for _, tt := range tests {
+		for caller, want := range tt.want {
+			t.Run(caller+" "+tt.method+" "+tt.path, func(t *testing.T) {
+				if got := f.do(caller, tt.method, tt.path, tt.body); got != want {
+					t.Errorf("got status %d, want %d", got, want)
+				}
+			})
+		}
+	}
+}
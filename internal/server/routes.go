@@ -2,27 +2,20 @@ package server
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"log/slog"
 	"money-minder/internal/auth"
 	"money-minder/internal/handlers"
 	"net/http"
-	"os"
 	"strings"
-
-	"github.com/golang-jwt/jwt/v5"
-)
-
-var (
-	jwtKey = []byte(os.Getenv("JWT_SECRET_KEY"))
 )
 
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -37,48 +30,112 @@ func (s *Server) RegisterRoutes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", s.healthHandler)
 
+	courseTeacher := s.teachesCourse(coursePathID)
+	bodyCourseTeacher := s.teachesCourse(s.courseBodyID)
+	attendanceTeacher := s.teachesCourse(s.attendanceCourseID)
+	classSessionTeacher := s.teachesCourse(s.classSessionCourseID)
+	checkInTeacher := s.teachesCourse(s.checkInCourseID)
+	justificationTeacher := s.teachesCourse(s.justificationCourseID)
+	ownsBodyCourse := allOf(isSelf(auth.RoleTeacher), bodyCourseTeacher)
+
 	// Student routes
-	mux.HandleFunc("POST /students", makeHandler(handlers.CreateStudent))
-	mux.HandleFunc("GET /students/{id}", makeHandler(handlers.GetStudentByID))
-	mux.HandleFunc("PATCH /students/{id}/courses", makeHandler(handlers.AddStudentCourse))
-	mux.HandleFunc("PATCH /students/{id}/attendances", makeHandler(handlers.AddStudentAttendance))
-	mux.HandleFunc("DELETE /students/{id}/courses", makeHandler(handlers.RemoveStudentCourse))
-	mux.HandleFunc("DELETE /students/{id}/attendances", makeHandler(handlers.RemoveStudentAttendance))
-	mux.HandleFunc("GET /students/{id}/courses", jwtMiddleware(makeHandler(handlers.GetAllCoursesByStudentID)))
-	mux.HandleFunc("GET /students/{id}/attendances", jwtMiddleware(makeHandler(handlers.GetAllAttendancesByStudentID)))
+	mux.HandleFunc("POST /students", s.authorize(makeHandler(s.handlers.CreateStudent), isAdmin))
+	mux.HandleFunc("GET /students/{id}", s.authorize(makeHandler(s.handlers.GetStudentByID), isAdmin, s.teachesStudent, isSelf(auth.RoleStudent)))
+	mux.HandleFunc("PATCH /students/{id}/courses", s.authorize(makeHandler(s.handlers.AddStudentCourse), isAdmin, bodyCourseTeacher))
+	mux.HandleFunc("PATCH /students/{id}/attendances", s.authorize(makeHandler(s.handlers.AddStudentAttendance), isAdmin))
+	mux.HandleFunc("DELETE /students/{id}/courses", s.authorize(makeHandler(s.handlers.RemoveStudentCourse), isAdmin, bodyCourseTeacher))
+	mux.HandleFunc("DELETE /students/{id}/attendances", s.authorize(makeHandler(s.handlers.RemoveStudentAttendance), isAdmin))
+	mux.HandleFunc("GET /students/{id}/courses", s.authorize(makeHandler(s.handlers.GetAllCoursesByStudentID), isAdmin, s.teachesStudent, isSelf(auth.RoleStudent)))
+	mux.HandleFunc("GET /students/{id}/risk", s.authorize(makeHandler(s.handlers.GetStudentRisk), isAdmin, isSelf(auth.RoleStudent)))
+	mux.HandleFunc("GET /students/{id}/attendances", s.authorize(makeHandler(s.handlers.GetAllAttendancesByStudentID), isAdmin, s.teachesStudent, isSelf(auth.RoleStudent)))
 
 	// Teacher routes
-	mux.HandleFunc("POST /teachers", makeHandler(handlers.CreateTeacher))
-	mux.HandleFunc("GET /teachers/{id}", makeHandler(handlers.GetTeacherByID))
-	mux.HandleFunc("PATCH /teachers/{id}/courses", makeHandler(handlers.AddTeacherCourse))
-	mux.HandleFunc("DELETE /teachers/{id}/courses", makeHandler(handlers.RemoveTeacherCourse))
-	mux.HandleFunc("GET /teachers/{id}/courses", jwtMiddleware(makeHandler(handlers.GetAllCoursesByTeacherID)))
+	mux.HandleFunc("POST /teachers", s.authorize(makeHandler(s.handlers.CreateTeacher), isAdmin))
+	mux.HandleFunc("GET /teachers/{id}", s.authorize(makeHandler(s.handlers.GetTeacherByID), isAdmin, isTeacher, isStudent))
+	mux.HandleFunc("PATCH /teachers/{id}/courses", s.authorize(makeHandler(s.handlers.AddTeacherCourse), isAdmin, ownsBodyCourse))
+	mux.HandleFunc("DELETE /teachers/{id}/courses", s.authorize(makeHandler(s.handlers.RemoveTeacherCourse), isAdmin, ownsBodyCourse))
+	mux.HandleFunc("GET /teachers/{id}/courses", s.authorize(makeHandler(s.handlers.GetAllCoursesByTeacherID), isAdmin, isSelf(auth.RoleTeacher)))
 
 	// Course routes
-	mux.HandleFunc("POST /courses", makeHandler(handlers.CreateCourse))
-	mux.HandleFunc("GET /courses/{id}", makeHandler(handlers.GetCourseByID))
-	mux.HandleFunc("DELETE /courses/{id}", makeHandler(handlers.DeleteCourse))
-	mux.HandleFunc("PATCH /courses/{id}/teacher", makeHandler(handlers.UpdateCourseTeacher))
-	mux.HandleFunc("PATCH /courses/{id}/students", makeHandler(handlers.AddCourseStudent))
-	mux.HandleFunc("DELETE /courses/{id}/students", makeHandler(handlers.RemoveCourseStudent))
-	mux.HandleFunc("GET /courses/{id}/students", jwtMiddleware(makeHandler(handlers.GetAllStudentsByCourseID)))
+	mux.HandleFunc("GET /courses", s.authorize(makeHandler(s.handlers.GetAllCourses), isAdmin, isTeacher))
+	mux.HandleFunc("POST /courses", s.authorize(makeHandler(s.handlers.CreateCourse), isAdmin, isTeacher))
+	mux.HandleFunc("GET /courses/{id}", s.authorize(makeHandler(s.handlers.GetCourseByID), isAdmin, isTeacher, isStudent))
+	mux.HandleFunc("DELETE /courses/{id}", s.authorize(makeHandler(s.handlers.DeleteCourse), isAdmin, courseTeacher))
+	mux.HandleFunc("PATCH /courses/{id}/teacher", s.authorize(makeHandler(s.handlers.UpdateCourseTeacher), isAdmin, courseTeacher))
+	mux.HandleFunc("PATCH /courses/{id}/students", s.authorize(makeHandler(s.handlers.AddCourseStudent), isAdmin, courseTeacher))
+	mux.HandleFunc("DELETE /courses/{id}/students", s.authorize(makeHandler(s.handlers.RemoveCourseStudent), isAdmin, courseTeacher))
+	mux.HandleFunc("PATCH /courses/{id}/location", s.authorize(makeHandler(s.handlers.UpdateCourseLocation), isAdmin, courseTeacher))
+	mux.HandleFunc("DELETE /courses/{id}/location", s.authorize(makeHandler(s.handlers.RemoveCourseLocation), isAdmin, courseTeacher))
+	mux.HandleFunc("PUT /courses/{id}/schedules", s.authorize(makeHandler(s.handlers.UpdateCourseSchedules), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/occurrences", s.authorize(makeHandler(s.handlers.GetCourseOccurrences), isAdmin, isTeacher, isStudent))
+	mux.HandleFunc("PATCH /courses/{id}/policy", s.authorize(makeHandler(s.handlers.UpdateCoursePolicy), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/stats", s.authorize(makeHandler(s.handlers.GetCourseStats), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/at-risk", s.authorize(makeHandler(s.handlers.GetCourseRisk), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/attendance/export", s.authorize(makeHandler(s.handlers.ExportCourseAttendance), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/attendance/report", s.authorize(makeHandler(s.handlers.GetCourseAttendanceReport), isAdmin, courseTeacher))
+	mux.HandleFunc("POST /courses/{id}/roster/import", s.authorize(makeHandler(s.handlers.ImportCourseRoster), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/students", s.authorize(makeHandler(s.handlers.GetAllStudentsByCourseID), isAdmin, courseTeacher))
+
+	// Class session routes
+	mux.HandleFunc("POST /courses/{id}/sessions", s.authorize(makeHandler(s.handlers.OpenClassSession), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /courses/{id}/sessions", s.authorize(makeHandler(s.handlers.GetAllClassSessionsByCourseID), isAdmin, courseTeacher))
+	mux.HandleFunc("PATCH /class-sessions/{id}/close", s.authorize(makeHandler(s.handlers.CloseClassSession), isAdmin, classSessionTeacher))
+	mux.HandleFunc("GET /class-sessions/{id}/roll", s.authorize(makeHandler(s.handlers.GetClassSessionRoll), isAdmin, classSessionTeacher))
+
+	// Check-in routes
+	mux.HandleFunc("POST /courses/{id}/checkin", s.authorize(makeHandler(s.handlers.OpenCheckIn), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /checkin/{id}/token", s.authorize(makeHandler(s.handlers.GetCheckInToken), isAdmin, checkInTeacher))
+	mux.HandleFunc("GET /checkin/{id}/qr", s.authorize(makeHandler(s.handlers.GetCheckInQR), isAdmin, checkInTeacher))
+	mux.HandleFunc("PATCH /checkin/{id}/close", s.authorize(makeHandler(s.handlers.CloseCheckIn), isAdmin, checkInTeacher))
+	mux.HandleFunc("POST /checkin/{id}/code", s.authorize(makeHandler(s.handlers.GenerateCheckInCode), isAdmin, checkInTeacher))
+	mux.HandleFunc("POST /checkin", s.authorize(makeHandler(s.handlers.CheckIn), isStudent))
+	mux.HandleFunc("POST /courses/{id}/checkin/code", s.authorize(makeHandler(s.handlers.CheckInWithCode), isStudent))
+	mux.HandleFunc("GET /courses/{id}/checkin/flagged", s.authorize(makeHandler(s.handlers.GetFlaggedCheckIns), isAdmin, courseTeacher))
+	mux.HandleFunc("PATCH /attendance/{id}/review", s.authorize(makeHandler(s.handlers.ReviewCheckIn), isAdmin, attendanceTeacher))
+
+	// Justification routes
+	mux.HandleFunc("POST /justifications", s.authorize(makeHandler(s.handlers.CreateJustification), isStudent))
+	mux.HandleFunc("GET /justifications/{id}", s.authorize(makeHandler(s.handlers.GetJustificationByID), isAdmin, justificationTeacher, s.submittedJustification))
+	mux.HandleFunc("GET /justifications/{id}/attachments/{attachmentId}", s.authorize(makeHandler(s.handlers.GetJustificationAttachment), isAdmin, justificationTeacher, s.submittedJustification))
+	mux.HandleFunc("PATCH /justifications/{id}/review", s.authorize(makeHandler(s.handlers.ReviewJustification), isAdmin, justificationTeacher))
+	mux.HandleFunc("GET /students/{id}/justifications", s.authorize(makeHandler(s.handlers.GetAllJustificationsByStudentID), isAdmin, isSelf(auth.RoleStudent)))
+	mux.HandleFunc("GET /courses/{id}/justifications", s.authorize(makeHandler(s.handlers.GetAllJustificationsByCourseID), isAdmin, courseTeacher))
 
 	// Attendance routes
-	mux.HandleFunc("POST /attendance", makeHandler(handlers.CreateAttendance))
-	mux.HandleFunc("PATCH /attendance/{id}", makeHandler(handlers.UpdateAttendance))
-	mux.HandleFunc("DELETE /attendance/{id}", makeHandler(handlers.DeleteAttendance))
-	mux.HandleFunc("GET /attendance/course/{id}", jwtMiddleware(makeHandler(handlers.GetAllAttendancesByCourseID)))
-	mux.HandleFunc("GET /attendance/student/{id}", jwtMiddleware(makeHandler(handlers.GetAllAttendancesByStudentID)))
+	mux.HandleFunc("POST /attendance", s.authorize(makeHandler(s.handlers.CreateAttendance), isAdmin, bodyCourseTeacher))
+	mux.HandleFunc("GET /attendance/{id}", s.authorize(makeHandler(s.handlers.GetAttendanceByID), isAdmin, attendanceTeacher, s.ownAttendance))
+	mux.HandleFunc("PATCH /attendance/{id}", s.authorize(makeHandler(s.handlers.UpdateAttendance), isAdmin, attendanceTeacher))
+	mux.HandleFunc("DELETE /attendance/{id}", s.authorize(makeHandler(s.handlers.DeleteAttendance), isAdmin, attendanceTeacher))
+	mux.HandleFunc("GET /attendance/course/{id}", s.authorize(makeHandler(s.handlers.GetAllAttendancesByCourseID), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /attendance/course/{id}/summary", s.authorize(makeHandler(s.handlers.GetCourseAttendanceSummary), isAdmin, courseTeacher))
+	mux.HandleFunc("GET /attendance/student/{id}", s.authorize(makeHandler(s.handlers.GetAllAttendancesByStudentID), isAdmin, s.teachesStudent, isSelf(auth.RoleStudent)))
+
+	// Admin routes
+	mux.HandleFunc("GET /admin/users", s.authorize(makeHandler(s.handlers.GetAllUsers), isAdmin))
+	mux.HandleFunc("GET /admin/users/{id}", s.authorize(makeHandler(s.handlers.GetUserByID), isAdmin))
+	mux.HandleFunc("PATCH /admin/users/{id}/disable", s.authorize(makeHandler(s.handlers.DisableUser), isAdmin))
+	mux.HandleFunc("PATCH /admin/users/{id}/enable", s.authorize(makeHandler(s.handlers.EnableUser), isAdmin))
+	mux.HandleFunc("PATCH /admin/users/{id}/approve", s.authorize(makeHandler(s.handlers.ApproveUser), isAdmin))
+	mux.HandleFunc("PATCH /admin/users/{id}/role", s.authorize(makeHandler(s.handlers.UpdateUserRole), isAdmin))
+	mux.HandleFunc("PATCH /admin/users/{id}/password", s.authorize(makeHandler(s.handlers.ResetUserPassword), isAdmin))
 
 	// Auth routes
-	mux.HandleFunc("POST /auth/register", makeHandler(handlers.Register))
-	mux.HandleFunc("POST /auth/login", makeHandler(handlers.Login))
+	mux.HandleFunc("POST /auth/register", makeHandler(s.handlers.Register))
+	mux.HandleFunc("POST /auth/login", makeHandler(s.handlers.Login))
+	mux.HandleFunc("POST /auth/refresh", makeHandler(s.handlers.Refresh))
+	mux.HandleFunc("POST /auth/logout", s.authorize(makeHandler(s.handlers.Logout), isAdmin, isTeacher, isStudent))
+	mux.HandleFunc("POST /auth/logout/all", s.authorize(makeHandler(s.handlers.LogoutAll), isAdmin, isTeacher, isStudent))
 
-	return corsMiddleware(mux)
+	return corsMiddleware(requestIDMiddleware(mux))
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, err := json.Marshal(s.db.Health())
+	health := map[string]string{"message": "It's healthy"}
+	if s.db != nil {
+		health = s.db.Health()
+	}
+
+	jsonResp, err := json.Marshal(health)
 	if err != nil {
 		log.Fatalf("error handling JSON marshal. Err: %v", err)
 	}
@@ -91,27 +148,24 @@ type apiFunc func(w http.ResponseWriter, r *http.Request) error
 func makeHandler(h apiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := h(w, r); err != nil {
-			if e, ok := err.(handlers.APIError); ok {
-				slog.Error("API Error", "err", e, "status", e.Status)
-				handlers.WriteJSON(w, e.Status, e)
-			}
+			writeError(w, r, err)
 		}
 	}
 }
 
-func jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func (s *Server) jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get the Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
+			writeError(w, r, handlers.APIError{Status: http.StatusUnauthorized, Msg: "Authorization header is missing"})
 			return
 		}
 
 		// Check if it starts with "Bearer "
 		const prefix = "Bearer "
 		if !strings.HasPrefix(authHeader, prefix) {
-			http.Error(w, "Invalid authorization format", http.StatusUnauthorized)
+			writeError(w, r, handlers.APIError{Status: http.StatusUnauthorized, Msg: "Invalid authorization format"})
 			return
 		}
 
@@ -119,23 +173,22 @@ func jwtMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, prefix)
 
 		// Parse and validate the token
-		claims := &auth.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg is what you expect
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtKey, nil
-		})
-
+		claims, err := s.tokens.Verify(tokenString)
 		if err != nil {
 			slog.Error("JWT Parse error", "error", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			writeError(w, r, handlers.APIError{Status: http.StatusUnauthorized, Msg: "Invalid token"})
 			return
 		}
 
-		if !token.Valid {
-			http.Error(w, "Token is not valid", http.StatusUnauthorized)
+		// Reject tokens whose session was logged out
+		active, err := s.store.Sessions.IsFamilyActive(claims.SessionID)
+		if err != nil {
+			slog.Error("Session lookup error", "error", err)
+			writeError(w, r, handlers.APIError{Status: http.StatusInternalServerError, Msg: "Could not verify session"})
+			return
+		}
+		if !active {
+			writeError(w, r, handlers.APIError{Status: http.StatusUnauthorized, Msg: "Session has been revoked"})
 			return
 		}
 
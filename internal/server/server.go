@@ -1,8 +1,16 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"money-minder/internal/auth"
 	"money-minder/internal/database"
+	"money-minder/internal/handlers"
+	"money-minder/internal/jobs"
+	"money-minder/internal/repositories"
+	"money-minder/internal/repositories/memory"
+	"money-minder/internal/storage"
 	"net/http"
 	"os"
 	"strconv"
@@ -12,16 +20,83 @@ import (
 )
 
 type Server struct {
-	port int
-	db   database.Service
+	port     int
+	db       database.Service
+	store    *repositories.Store
+	tokens   *auth.TokenService
+	handlers *handlers.Handler
 }
 
+// NewServer builds the API from the environment: PORT and DB_DRIVER pick the
+// listen port and the storage backend, JWT_* configure the token service and
+// ABSENCE_* and RISK_INTERVAL the background jobs, STORAGE_DIR where
+// uploads go and ADMIN_* the first admin account.
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	NewServer := &Server{
-		port: port,
 
-		db: database.New(),
+	cfg, err := auth.TokenConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tokens, err := auth.NewTokenService(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files, err := storage.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	absences, err := jobs.AbsenceConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	riskInterval, err := jobs.RiskIntervalFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	driver, err := database.Driver()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var db database.Service
+	var store *repositories.Store
+	if driver == database.DriverMemory {
+		store = memory.NewStore()
+	} else {
+		db = database.New()
+		if err := repositories.Migrate(db); err != nil {
+			log.Fatal(err)
+		}
+		if err := database.EnsureIndexes(context.Background(), db, database.Indexes); err != nil {
+			log.Fatal(err)
+		}
+		store = repositories.NewMongoStore(db)
+	}
+
+	if err := bootstrapAdmin(store); err != nil {
+		log.Fatal(err)
+	}
+
+	jobs.NewAbsenceMarker(store, absences).Start(context.Background())
+	jobs.NewRiskEvaluator(store, riskInterval).Start(context.Background())
+
+	return New(port, db, store, tokens, files)
+}
+
+// New builds a server around an explicit store and file storage. db is only
+// used for health checks and may be nil when the store does not live in
+// MongoDB.
+func New(port int, db database.Service, store *repositories.Store, tokens *auth.TokenService, files storage.Storage) *http.Server {
+	NewServer := &Server{
+		port:     port,
+		db:       db,
+		store:    store,
+		tokens:   tokens,
+		handlers: handlers.New(store, tokens, files),
 	}
 
 	// Declare Server config
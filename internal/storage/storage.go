@@ -0,0 +1,100 @@
+// Package storage keeps uploaded files, such as the medical certificates
+// attached to absence justifications, out of the database.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Storage saves and serves files by key. Keys are chosen by the caller and
+// must be plain names without path separators.
+type Storage interface {
+	Save(key string, r io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Disk stores files as plain files under a root directory.
+type Disk struct {
+	root string
+}
+
+// NewDisk returns a Disk rooted at dir, creating it if needed.
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create storage dir: %w", err)
+	}
+	return &Disk{root: dir}, nil
+}
+
+// FromEnv returns a Disk rooted at STORAGE_DIR, defaulting to ./uploads.
+func FromEnv() (*Disk, error) {
+	dir := os.Getenv("STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return NewDisk(dir)
+}
+
+func (d *Disk) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(d.root, key), nil
+}
+
+func (d *Disk) Save(key string, r io.Reader) (int64, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (d *Disk) Open(key string) (io.ReadCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (d *Disk) Delete(key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
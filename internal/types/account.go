@@ -0,0 +1,49 @@
+package types
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Account statuses. Only active accounts can log in; teachers who register
+// themselves stay pending until an admin approves them. Accounts stored
+// before statuses existed have none and are active.
+const (
+	AccountActive   = "active"
+	AccountPending  = "pending"
+	AccountDisabled = "disabled"
+)
+
+// ValidAccountStatus reports whether s is one of the account statuses.
+func ValidAccountStatus(s string) bool {
+	switch s {
+	case AccountActive, AccountPending, AccountDisabled:
+		return true
+	}
+	return false
+}
+
+// AccountStatus returns the status of an account stored with status s.
+func AccountStatus(s string) string {
+	if s == "" {
+		return AccountActive
+	}
+	return s
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in, so
+// A@x.com and a@x.com are the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Admin manages accounts. Admins live in their own collection, like
+// students and teachers.
+type Admin struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name     string             `json:"name" bson:"name"`
+	Email    string             `json:"email" bson:"email"`
+	Password string             `json:"-" bson:"password"`
+	Status   string             `json:"status,omitempty" bson:"status,omitempty"`
+}
@@ -6,11 +6,171 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// AttendanceTypeAuto marks absences recorded by the server when a class ended.
+const AttendanceTypeAuto = "auto"
+
+const (
+	StatusPresent   = "present"
+	StatusAbsent    = "absent"
+	StatusLate      = "late"
+	StatusExcused   = "excused"
+	StatusLeftEarly = "left-early"
+	StatusRemote    = "remote"
+)
+
+// ValidStatus reports whether s is one of the attendance statuses.
+func ValidStatus(s string) bool {
+	switch s {
+	case StatusPresent, StatusAbsent, StatusLate, StatusExcused, StatusLeftEarly, StatusRemote:
+		return true
+	}
+	return false
+}
+
+// StatusAttended reports whether a student with status s was in class,
+// which is what Present mirrors.
+func StatusAttended(s string) bool {
+	return s != StatusAbsent && s != StatusExcused
+}
+
 type Attendance struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	CourseID  primitive.ObjectID `json:"courseId" bson:"course_id"`
 	StudentID primitive.ObjectID `json:"studentId" bson:"student_id"`
+	SessionID primitive.ObjectID `json:"sessionId,omitempty" bson:"session_id,omitempty"`
 	Type      string             `json:"type" bson:"type"`
 	Date      time.Time          `json:"date" bson:"date"`
-	Present   bool               `json:"present" bson:"present"`
+	// Day is Date as YYYY-MM-DD in the course's timezone. Records without a
+	// session are kept to one per student, course and day.
+	Day    string `json:"day" bson:"day"`
+	Status string `json:"status" bson:"status"`
+	// Present is kept in sync with Status for clients that predate it.
+	Present     bool       `json:"present" bson:"present"`
+	MinutesLate int        `json:"minutesLate,omitempty" bson:"minutes_late,omitempty"`
+	CheckInAt   *time.Time `json:"checkInAt,omitempty" bson:"check_in_at,omitempty"`
+	CheckOutAt  *time.Time `json:"checkOutAt,omitempty" bson:"check_out_at,omitempty"`
+	Note        string     `json:"note,omitempty" bson:"note,omitempty"`
+	GeoCheck    *GeoCheck  `json:"geoCheck,omitempty" bson:"geo_check,omitempty"`
+}
+
+// SetDay fills Day from Date as seen in loc, the course's timezone.
+func (a *Attendance) SetDay(loc *time.Location) {
+	a.Day = a.Date.In(loc).Format(time.DateOnly)
+}
+
+// SetStatus changes the status and the Present flag that follows it.
+func (a *Attendance) SetStatus(status string) {
+	a.Status = status
+	a.Present = StatusAttended(status)
+	if status != StatusLate {
+		a.MinutesLate = 0
+	}
+}
+
+// AttendancePolicy is how a course turns attendance records into absences.
+type AttendancePolicy struct {
+	// LateAfterMinutes is how long after the start of class a check-in
+	// still counts as present rather than late.
+	LateAfterMinutes int `json:"lateAfterMinutes" bson:"late_after_minutes" validate:"min=0"`
+	// LatesPerAbsence is how many lates add up to one absence; zero means
+	// lates never turn into absences.
+	LatesPerAbsence int `json:"latesPerAbsence" bson:"lates_per_absence" validate:"min=0"`
+	// MinAttendancePercent is the attendance a student needs to pass; zero
+	// turns the check off.
+	MinAttendancePercent float64 `json:"minAttendancePercent" bson:"min_attendance_percent" validate:"min=0,max=100"`
+	// MaxConsecutiveAbsences puts a student at risk once they miss this many
+	// classes in a row; zero turns the check off.
+	MaxConsecutiveAbsences int `json:"maxConsecutiveAbsences" bson:"max_consecutive_absences" validate:"min=0"`
+	// WarningMarginPercent is how close to MinAttendancePercent a student
+	// may get before they are warned.
+	WarningMarginPercent float64 `json:"warningMarginPercent" bson:"warning_margin_percent" validate:"min=0,max=100"`
+}
+
+// DefaultAttendancePolicy applies to courses that did not set their own.
+var DefaultAttendancePolicy = AttendancePolicy{
+	LateAfterMinutes:       10,
+	LatesPerAbsence:        3,
+	MinAttendancePercent:   75,
+	MaxConsecutiveAbsences: 3,
+	WarningMarginPercent:   5,
+}
+
+// AttendanceSummary counts one student's records in a course.
+type AttendanceSummary struct {
+	StudentID primitive.ObjectID `json:"studentId" bson:"_id"`
+	Total     int                `json:"total" bson:"total"`
+	Present   int                `json:"present" bson:"present"`
+	Absent    int                `json:"absent" bson:"absent"`
+	Late      int                `json:"late" bson:"late"`
+	Excused   int                `json:"excused" bson:"excused"`
+	LeftEarly int                `json:"leftEarly" bson:"left_early"`
+	Remote    int                `json:"remote" bson:"remote"`
+	// EffectiveAbsences adds the absences that accumulated lates are worth
+	// under the course policy, see ApplyPolicy.
+	EffectiveAbsences int `json:"effectiveAbsences" bson:"-"`
+}
+
+// ApplyPolicy computes EffectiveAbsences under policy.
+func (s *AttendanceSummary) ApplyPolicy(policy AttendancePolicy) {
+	s.EffectiveAbsences = s.Absent
+	if policy.LatesPerAbsence > 0 {
+		s.EffectiveAbsences += s.Late / policy.LatesPerAbsence
+	}
+}
+
+// AttendanceRate is the percentage of classes attended, leaving excused ones
+// out, or nil when there is nothing to count yet.
+func (s *AttendanceSummary) AttendanceRate() *float64 {
+	counted := s.Total - s.Excused
+	if counted <= 0 {
+		return nil
+	}
+	rate := 100 * float64(s.Present+s.Late+s.LeftEarly+s.Remote) / float64(counted)
+	return &rate
+}
+
+// EffectiveAttendanceRate is AttendanceRate after counting the absences that
+// lates are worth, see ApplyPolicy, or nil when there is nothing to count.
+func (s *AttendanceSummary) EffectiveAttendanceRate() *float64 {
+	counted := s.Total - s.Excused
+	if counted <= 0 {
+		return nil
+	}
+	rate := 100 * float64(counted-s.EffectiveAbsences) / float64(counted)
+	return &rate
+}
+
+// SummarizeAttendances counts the records of each student, in the order
+// students first appear.
+func SummarizeAttendances(Attendances []*Attendance) []AttendanceSummary {
+	summaries := []AttendanceSummary{}
+	index := make(map[primitive.ObjectID]int)
+
+	for _, a := range Attendances {
+		i, ok := index[a.StudentID]
+		if !ok {
+			i = len(summaries)
+			index[a.StudentID] = i
+			summaries = append(summaries, AttendanceSummary{StudentID: a.StudentID})
+		}
+
+		s := &summaries[i]
+		s.Total++
+		switch a.Status {
+		case StatusPresent:
+			s.Present++
+		case StatusAbsent:
+			s.Absent++
+		case StatusLate:
+			s.Late++
+		case StatusExcused:
+			s.Excused++
+		case StatusLeftEarly:
+			s.LeftEarly++
+		case StatusRemote:
+			s.Remote++
+		}
+	}
+
+	return summaries
 }
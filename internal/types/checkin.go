@@ -0,0 +1,42 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AttendanceTypeQR   = "qr"
+	AttendanceTypeCode = "code"
+)
+
+// CheckInWindow is the period during which students of a course can check
+// themselves in to one class session. Its Key signs the rotating QR tokens.
+type CheckInWindow struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CourseID      primitive.ObjectID `json:"courseId" bson:"course_id"`
+	SessionID     primitive.ObjectID `json:"sessionId" bson:"session_id"`
+	OpenedBy      primitive.ObjectID `json:"openedBy" bson:"opened_by"`
+	OpensAt       time.Time          `json:"opensAt" bson:"opens_at"`
+	ClosesAt      time.Time          `json:"closesAt" bson:"closes_at"`
+	RotateSeconds int                `json:"rotateSeconds" bson:"rotate_seconds"`
+	Key           []byte             `json:"-" bson:"key"`
+	CodeHash      string             `json:"-" bson:"code_hash,omitempty"`
+	CodeExpiresAt *time.Time         `json:"codeExpiresAt,omitempty" bson:"code_expires_at,omitempty"`
+	ClosedAt      *time.Time         `json:"closedAt,omitempty" bson:"closed_at,omitempty"`
+}
+
+// IsOpen reports whether students can check in at t.
+func (w *CheckInWindow) IsOpen(t time.Time) bool {
+	return w.ClosedAt == nil && !t.Before(w.OpensAt) && t.Before(w.ClosesAt)
+}
+
+// CheckInAttempt counts a student's wrong numeric codes on one window.
+type CheckInAttempt struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WindowID    primitive.ObjectID `json:"windowId" bson:"window_id"`
+	StudentID   primitive.ObjectID `json:"studentId" bson:"student_id"`
+	Failures    int                `json:"failures" bson:"failures"`
+	LockedUntil *time.Time         `json:"lockedUntil,omitempty" bson:"locked_until,omitempty"`
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ClassSessionOpen   = "open"
+	ClassSessionClosed = "closed"
+
+	// ClassSessionTypeScheduled marks sessions created from the course
+	// schedule because nobody opened one for that class.
+	ClassSessionTypeScheduled = "scheduled"
+)
+
+// ClassSession is one concrete meeting of a course, such as "the Tuesday
+// 10:00 lecture". Attendance records point at the session they belong to.
+type ClassSession struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CourseID primitive.ObjectID `json:"courseId" bson:"course_id"`
+	Start    time.Time          `json:"start" bson:"start"`
+	End      time.Time          `json:"end" bson:"end"`
+	Room     string             `json:"room" bson:"room"`
+	Type     string             `json:"type" bson:"type"`
+	Status   string             `json:"status" bson:"status"`
+	// AbsencesMarkedAt is set once students who did not check in have been
+	// recorded as absent.
+	AbsencesMarkedAt *time.Time `json:"absencesMarkedAt,omitempty" bson:"absences_marked_at,omitempty"`
+}
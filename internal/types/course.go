@@ -7,6 +7,19 @@ type Course struct {
 	Name      string             `json:"name" bson:"name"`
 	Code      string             `json:"code" bson:"code"`
 	Teacher   primitive.ObjectID `json:"teacher" bson:"teacher,omitempty"`
-	Students  []Student          `json:"students,omitempty" bson:"students,omitempty"`
-	Schedules []string           `json:"schedules,omitempty" bson:"schedules,omitempty"`
+	Schedules []ScheduleSlot     `json:"schedules,omitempty" bson:"schedules,omitempty"`
+	// LegacySchedules keeps the free-form schedules courses had before
+	// ScheduleSlot existed, so nobody loses what they typed.
+	LegacySchedules []string          `json:"legacySchedules,omitempty" bson:"legacy_schedules,omitempty"`
+	Location        *GeoFence         `json:"location,omitempty" bson:"location,omitempty"`
+	Policy          *AttendancePolicy `json:"policy,omitempty" bson:"policy,omitempty"`
+}
+
+// AttendancePolicy returns the course policy, or the default one when the
+// course has none.
+func (c *Course) AttendancePolicy() AttendancePolicy {
+	if c.Policy != nil {
+		return *c.Policy
+	}
+	return DefaultAttendancePolicy
 }
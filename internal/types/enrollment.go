@@ -0,0 +1,28 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EnrollmentRoleStudent = "student"
+	EnrollmentRoleTeacher = "teacher"
+
+	EnrollmentActive  = "active"
+	EnrollmentDropped = "dropped"
+)
+
+// Enrollment links a user to a course. It is the only record of who takes
+// or teaches a course besides Course.Teacher, the course owner. Dropping
+// out keeps the enrollment with the dropped status.
+type Enrollment struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CourseID   primitive.ObjectID `json:"courseId" bson:"course_id"`
+	UserID     primitive.ObjectID `json:"userId" bson:"user_id"`
+	Role       string             `json:"role" bson:"role"`
+	Status     string             `json:"status" bson:"status"`
+	EnrolledAt time.Time          `json:"enrolledAt" bson:"enrolled_at"`
+	DroppedAt  *time.Time         `json:"droppedAt,omitempty" bson:"dropped_at,omitempty"`
+}
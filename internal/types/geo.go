@@ -0,0 +1,42 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	GeoFenceReject = "reject"
+	GeoFenceFlag   = "flag"
+)
+
+// GeoFence is the area students must be in to check in. Mode decides what
+// happens to check-ins outside of it.
+type GeoFence struct {
+	Latitude     float64 `json:"latitude" bson:"latitude"`
+	Longitude    float64 `json:"longitude" bson:"longitude"`
+	RadiusMeters float64 `json:"radiusMeters" bson:"radius_meters"`
+	Mode         string  `json:"mode" bson:"mode"`
+}
+
+const (
+	GeoInside    = "inside"
+	GeoUncertain = "uncertain"
+	GeoOutside   = "outside"
+	GeoMissing   = "missing"
+)
+
+// GeoCheck is the server-side location decision stored with a check-in.
+type GeoCheck struct {
+	Latitude       float64             `json:"latitude" bson:"latitude"`
+	Longitude      float64             `json:"longitude" bson:"longitude"`
+	AccuracyMeters float64             `json:"accuracyMeters" bson:"accuracy_meters"`
+	DistanceMeters float64             `json:"distanceMeters" bson:"distance_meters"`
+	RadiusMeters   float64             `json:"radiusMeters" bson:"radius_meters"`
+	Decision       string              `json:"decision" bson:"decision"`
+	Flagged        bool                `json:"flagged" bson:"flagged"`
+	ReviewedBy     *primitive.ObjectID `json:"reviewedBy,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time          `json:"reviewedAt,omitempty" bson:"reviewed_at,omitempty"`
+	Approved       *bool               `json:"approved,omitempty" bson:"approved,omitempty"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	JustificationPending  = "pending"
+	JustificationApproved = "approved"
+	JustificationRejected = "rejected"
+)
+
+// Justification is a student's excuse for missing a course between From and
+// Until. Approving it turns the absences in that range into excused ones.
+type Justification struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	StudentID     primitive.ObjectID  `json:"studentId" bson:"student_id"`
+	CourseID      primitive.ObjectID  `json:"courseId" bson:"course_id"`
+	Reason        string              `json:"reason" bson:"reason"`
+	From          time.Time           `json:"from" bson:"from"`
+	Until         time.Time           `json:"until" bson:"until"`
+	Attachments   []Attachment        `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	Status        string              `json:"status" bson:"status"`
+	CreatedAt     time.Time           `json:"createdAt" bson:"created_at"`
+	ReviewedBy    *primitive.ObjectID `json:"reviewedBy,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time          `json:"reviewedAt,omitempty" bson:"reviewed_at,omitempty"`
+	ReviewComment string              `json:"reviewComment,omitempty" bson:"review_comment,omitempty"`
+}
+
+// Covers reports whether t falls between From and Until, inclusive.
+func (j *Justification) Covers(t time.Time) bool {
+	return !t.Before(j.From) && !t.After(j.Until)
+}
+
+// Attachment is an uploaded file; Key locates it in storage.
+type Attachment struct {
+	ID          string `json:"id" bson:"id"`
+	Key         string `json:"-" bson:"key"`
+	Name        string `json:"name" bson:"name"`
+	ContentType string `json:"contentType" bson:"content_type"`
+	Size        int64  `json:"size" bson:"size"`
+}
@@ -0,0 +1,10 @@
+package types
+
+import "time"
+
+// Lease lets one server replica at a time run a background job.
+type Lease struct {
+	ID        string    `json:"id" bson:"_id"`
+	Holder    string    `json:"holder" bson:"holder"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expires_at"`
+}
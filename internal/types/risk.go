@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RiskOK      = "ok"
+	RiskWarning = "warning"
+	RiskAtRisk  = "at-risk"
+)
+
+// RiskAssessment is how close a student is to failing a course on
+// attendance under the course policy.
+type RiskAssessment struct {
+	ID                  primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	CourseID            primitive.ObjectID `json:"courseId" bson:"course_id"`
+	StudentID           primitive.ObjectID `json:"studentId" bson:"student_id"`
+	Name                string             `json:"name" bson:"name"`
+	Level               string             `json:"level" bson:"level"`
+	AttendanceRate      *float64           `json:"attendanceRate" bson:"attendance_rate"`
+	EffectiveAbsences   int                `json:"effectiveAbsences" bson:"effective_absences"`
+	ConsecutiveAbsences int                `json:"consecutiveAbsences" bson:"consecutive_absences"`
+	Reasons             []string           `json:"reasons,omitempty" bson:"reasons,omitempty"`
+	// Since is when the student left the ok level, as first seen by the
+	// periodic evaluation.
+	Since       *time.Time `json:"since,omitempty" bson:"since,omitempty"`
+	EvaluatedAt time.Time  `json:"evaluatedAt" bson:"evaluated_at"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// ScheduleSlot is one weekly meeting of a course, e.g. Tuesdays 10:00-11:30
+// in room A-101. Times are wall-clock times in Timezone and dates are
+// YYYY-MM-DD in that same timezone.
+type ScheduleSlot struct {
+	Weekday    string   `json:"weekday" bson:"weekday"`
+	StartTime  string   `json:"startTime" bson:"start_time"`
+	EndTime    string   `json:"endTime" bson:"end_time"`
+	Timezone   string   `json:"timezone" bson:"timezone"`
+	Room       string   `json:"room,omitempty" bson:"room,omitempty"`
+	ValidFrom  string   `json:"validFrom,omitempty" bson:"valid_from,omitempty"`
+	ValidUntil string   `json:"validUntil,omitempty" bson:"valid_until,omitempty"`
+	Exceptions []string `json:"exceptions,omitempty" bson:"exceptions,omitempty"`
+}
+
+// Occurrence is a concrete meeting produced by expanding a ScheduleSlot.
+type Occurrence struct {
+	Slot  int       `json:"slot"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Room  string    `json:"room,omitempty"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is one refresh token. Rotating a token creates a new Session in
+// the same family and marks the previous one as used.
+type Session struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	FamilyID  primitive.ObjectID `json:"familyId" bson:"family_id"`
+	UserID    primitive.ObjectID `json:"userId" bson:"user_id"`
+	Email     string             `json:"email" bson:"email"`
+	Role      string             `json:"role" bson:"role"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	CreatedAt time.Time          `json:"createdAt" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expiresAt" bson:"expires_at"`
+	UsedAt    *time.Time         `json:"usedAt,omitempty" bson:"used_at,omitempty"`
+	RevokedAt *time.Time         `json:"revokedAt,omitempty" bson:"revoked_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// CourseStats is the attendance picture of a course: one line per enrolled
+// student and the averages across them.
+type CourseStats struct {
+	CourseID     primitive.ObjectID `json:"courseId"`
+	SessionsHeld int64              `json:"sessionsHeld"`
+	Students     []StudentStats     `json:"students"`
+	Average      StatsAverage       `json:"average"`
+}
+
+type StudentStats struct {
+	AttendanceSummary
+	Name           string   `json:"name"`
+	AttendanceRate *float64 `json:"attendanceRate"`
+}
+
+// StatsAverage holds per-student means. AttendanceRate only averages
+// students that have a rate.
+type StatsAverage struct {
+	Present        float64  `json:"present"`
+	Absent         float64  `json:"absent"`
+	Late           float64  `json:"late"`
+	Excused        float64  `json:"excused"`
+	AttendanceRate *float64 `json:"attendanceRate"`
+}
@@ -6,7 +6,8 @@ type Student struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name        string             `json:"name" bson:"name"`
 	Email       string             `json:"email" bson:"email"`
-	Password    string             `json:"password" bson:"password"`
-	Courses     []Course           `json:"courses,omitempty" bson:"courses,omitempty"`
+	Password    string             `json:"-" bson:"password"`
+	Status      string             `json:"status,omitempty" bson:"status,omitempty"`
+	Number      string             `json:"studentNumber,omitempty" bson:"student_number,omitempty"`
 	Attendances []Attendance       `json:"attendances,omitempty" bson:"attendances,omitempty"`
 }
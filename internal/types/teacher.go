@@ -6,6 +6,6 @@ type Teacher struct {
 	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name     string             `json:"name" bson:"name"`
 	Email    string             `json:"email" bson:"email"`
-	Password string             `json:"password,omitempty" bson:"password"`
-	Courses  []Course           `json:"courses,omitempty" bson:"courses,omitempty"`
+	Password string             `json:"-" bson:"password"`
+	Status   string             `json:"status,omitempty" bson:"status,omitempty"`
 }
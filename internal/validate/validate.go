@@ -0,0 +1,220 @@
+// Package validate checks request structs against the rules declared in
+// their validate tags, e.g.
+//
+//	Email string `json:"email" validate:"required,email,max=254"`
+//
+// Rules are separated by commas:
+//
+//	required     the value is not empty (zero, blank string, nil or empty list)
+//	email        a bare address such as ana@example.com
+//	objectid     a hex MongoDB ObjectID
+//	min=N max=N  bounds on the length of strings and lists or on numbers
+//	oneof=a b c  one of the space-separated values
+//
+// Every rule but required accepts an empty value, so optional fields only
+// need their format rules. Nested structs, pointers to them and lists of
+// them are checked as well. Errors name fields by their JSON path, like
+// schedules[1].startTime.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"money-minder/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Struct checks v, a struct, a pointer to one or a list of them, and returns
+// every violation found. The result is never nil so callers can add checks
+// of their own before calling Err.
+func Struct(v any) *repositories.ValidationError {
+	errs := &repositories.ValidationError{}
+	walk(errs, reflect.ValueOf(v), "")
+	return errs
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// walk descends into structs and lists, checking each field's rules.
+func walk(errs *repositories.ValidationError, v reflect.Value, path string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walk(errs, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Struct:
+		if v.Type() == timeType || v.Type() == objectIDType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, skip := jsonName(f)
+			if skip {
+				continue
+			}
+
+			field := v.Field(i)
+			if f.Anonymous && name == "" {
+				walk(errs, field, path)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+
+			if tag := f.Tag.Get("validate"); tag != "" {
+				if msg := check(field, tag); msg != "" {
+					errs.Add(name, msg)
+					continue
+				}
+			}
+			walk(errs, field, name)
+		}
+	}
+}
+
+// jsonName returns the name encoding/json gives the field. Anonymous
+// fields without a name are flattened into their parent.
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+// check applies the rules in tag to v and describes the first one it
+// breaks, or returns "" when v follows them all.
+func check(v reflect.Value, tag string) string {
+	// A non-nil pointer means the field was sent, which is all required
+	// asks of it: a false *bool still counts.
+	present := false
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if strings.Contains(","+tag+",", ",required,") {
+				return "is required"
+			}
+			return ""
+		}
+		v, present = v.Elem(), true
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		if name == "required" {
+			if !present && empty(v) {
+				return "is required"
+			}
+			continue
+		}
+		if empty(v) {
+			return ""
+		}
+
+		switch name {
+		case "email":
+			addr, err := mail.ParseAddress(v.String())
+			if err != nil || addr.Address != v.String() {
+				return "must be a valid email address"
+			}
+		case "objectid":
+			if _, err := primitive.ObjectIDFromHex(v.String()); err != nil {
+				return "must be a valid ID"
+			}
+		case "oneof":
+			if !contains(strings.Fields(arg), fmt.Sprint(v.Interface())) {
+				return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+			}
+		case "min", "max":
+			if msg := bound(v, name, arg); msg != "" {
+				return msg
+			}
+		default:
+			panic("validate: unknown rule " + rule)
+		}
+	}
+	return ""
+}
+
+// bound checks a min or max rule against the length of strings and lists
+// and against the value of numbers.
+func bound(v reflect.Value, rule, arg string) string {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic("validate: bad " + rule + " argument " + arg)
+	}
+
+	var n float64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		panic("validate: " + rule + " does not apply to " + v.Kind().String())
+	}
+
+	switch {
+	case rule == "min" && n < limit && unit != "":
+		return "must have at least " + arg + unit
+	case rule == "min" && n < limit:
+		return "must be at least " + arg
+	case rule == "max" && n > limit && unit != "":
+		return "must have at most " + arg + unit
+	case rule == "max" && n > limit:
+		return "must be at most " + arg
+	}
+	return ""
+}
+
+func empty(v reflect.Value) bool {
+	if v.Kind() == reflect.String {
+		return strings.TrimSpace(v.String()) == ""
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.IsZero()
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}